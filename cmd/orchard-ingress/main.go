@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"orchard/ingress"
+)
+
+func main() {
+	configPath := flag.String("config", "ingress.json", "JSON file of ingress routing rules")
+	managerAddr := flag.String("manager", "127.0.0.1:9300", "address of the manager API")
+	addr := flag.String("addr", ":8080", "address to serve proxied traffic on")
+	flag.Parse()
+
+	config, err := ingress.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	in := ingress.New(*managerAddr, config)
+	go in.RefreshPeriodically()
+
+	log.Printf("Ingress listening on %s with %d rules\n", *addr, len(config.Rules))
+	log.Fatal(http.ListenAndServe(*addr, in))
+}
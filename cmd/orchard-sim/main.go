@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"orchard/scheduler"
+	"orchard/simulator"
+	"strings"
+)
+
+func newScheduler(name string) (scheduler.Scheduler, error) {
+	switch name {
+	case "round-robin":
+		return &scheduler.RoundRobin{}, nil
+	case "epvm":
+		return &scheduler.Epvm{}, nil
+	case "bin-pack":
+		return &scheduler.BinPack{}, nil
+	case "spread":
+		return &scheduler.Spread{}, nil
+	}
+
+	if strings.HasSuffix(name, ".json") {
+		return scheduler.NewFrameworkFromFile(name)
+	}
+	return nil, fmt.Errorf("unknown scheduler %s", name)
+}
+
+func main() {
+	clusterPath := flag.String("cluster", "cluster.json", "JSON file describing the simulated nodes")
+	tracePath := flag.String("trace", "trace.jsonl", "JSONL file of task arrivals")
+	schedulers := flag.String("schedulers", "round-robin,epvm", "comma separated schedulers or framework config files to compare")
+	flag.Parse()
+
+	cluster, err := simulator.LoadCluster(*clusterPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	trace, err := simulator.LoadTrace(*tracePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, name := range strings.Split(*schedulers, ",") {
+		sched, err := newScheduler(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(simulator.Run(sched, cluster, trace))
+	}
+}
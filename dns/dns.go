@@ -0,0 +1,328 @@
+package dns
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	DefaultDomain  = "orchard.local"
+	DefaultTTL     = 5
+	ForwardTimeout = 2 * time.Second
+)
+
+// ResolvConf is where the host's resolvers are read from when a Server has no Upstream.
+var ResolvConf = "/etc/resolv.conf"
+
+// Endpoint is one healthy task behind a name, reachable at IP on the host ports its
+// container ports (such as "80/tcp") are published on.
+type Endpoint struct {
+	Name  string
+	IP    net.IP
+	Ports map[string]uint16
+}
+
+// Resolver looks up the endpoints for a name relative to the domain, such as "api".
+// The bool reports whether the name is known at all, even with no healthy endpoints.
+type Resolver interface {
+	Resolve(name string) ([]Endpoint, bool)
+}
+
+// Server answers A and SRV queries under Domain over UDP and forwards every other query to
+// the Upstream resolvers, so containers using it can still resolve hosts outside the cluster.
+//
+//	api.orchard.local              A   address of every ready task of service api
+//	api.orchard.local              SRV every published port of those tasks
+//	_80._tcp.api.orchard.local     SRV only tasks' container port 80/tcp
+type Server struct {
+	Addr     string
+	Domain   string
+	TTL      uint32
+	Resolver Resolver
+	// Upstream lists the "host:port" resolvers to forward to, by default the host's nameservers.
+	Upstream []string
+}
+
+func (s *Server) ListenAndServe() error {
+	conn, err := net.ListenPacket("udp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if s.Upstream == nil {
+		s.Upstream = hostResolvers(ResolvConf)
+	}
+
+	log.Printf("Serving DNS for %s on %s, forwarding to %v\n", s.domain(), s.Addr, s.Upstream)
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("Error reading DNS query: %v\n", err)
+			continue
+		}
+
+		// Each query is answered on its own goroutine so a slow upstream does not hold up the rest.
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		go func(query []byte, addr net.Addr) {
+			resp, err := s.answer(query)
+			if err != nil {
+				log.Printf("Error answering DNS query from %v: %v\n", addr, err)
+				return
+			}
+			conn.WriteTo(resp, addr)
+		}(query, addr)
+	}
+}
+
+// hostResolvers reads the nameservers of a resolv.conf file.
+func hostResolvers(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error reading resolvers from %s: %v\n", path, err)
+		return []string{}
+	}
+	defer f.Close()
+
+	resolvers := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if ip := net.ParseIP(fields[1]); ip != nil {
+			resolvers = append(resolvers, net.JoinHostPort(ip.String(), "53"))
+		}
+	}
+	return resolvers
+}
+
+func (s *Server) domain() string {
+	if s.Domain == "" {
+		return DefaultDomain
+	}
+	return strings.Trim(s.Domain, ".")
+}
+
+func (s *Server) ttl() uint32 {
+	if s.TTL == 0 {
+		return DefaultTTL
+	}
+	return s.TTL
+}
+
+func (s *Server) answer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.inDomain(q.Name.String()) {
+		resp, err := s.forward(query)
+		if err == nil {
+			return resp, nil
+		}
+		log.Printf("Error forwarding DNS query for %s: %v\n", q.Name, err)
+		return s.failure(header, q)
+	}
+
+	name, port, ok := s.parseName(q.Name.String())
+	var endpoints []Endpoint
+	if ok {
+		endpoints, ok = s.Resolver.Resolve(name)
+	}
+
+	rcode := dnsmessage.RCodeSuccess
+	if !ok {
+		rcode = dnsmessage.RCodeNameError
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:               header.ID,
+		Response:         true,
+		Authoritative:    true,
+		RecursionDesired: header.RecursionDesired,
+		RCode:            rcode,
+	})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return b.Finish()
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	switch q.Type {
+	case dnsmessage.TypeA:
+		err = s.addresses(&b, q.Name, endpoints)
+	case dnsmessage.TypeSRV:
+		err = s.services(&b, q.Name, port, endpoints)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// forward relays a query to each upstream resolver in turn until one answers.
+func (s *Server) forward(query []byte) ([]byte, error) {
+	err := errors.New("no upstream resolvers")
+	for _, upstream := range s.Upstream {
+		var resp []byte
+		if resp, err = exchange(upstream, query); err == nil {
+			return resp, nil
+		}
+	}
+	return nil, err
+}
+
+func exchange(addr string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", addr, ForwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(ForwardTimeout))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// failure answers a query with SERVFAIL.
+func (s *Server) failure(header dnsmessage.Header, q dnsmessage.Question) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:               header.ID,
+		Response:         true,
+		RecursionDesired: header.RecursionDesired,
+		RCode:            dnsmessage.RCodeServerFailure,
+	})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+func (s *Server) inDomain(fqdn string) bool {
+	name := strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	return name == s.domain() || strings.HasSuffix(name, "."+s.domain())
+}
+
+// parseName strips the domain and any _port._proto service prefix off a query name.
+func (s *Server) parseName(fqdn string) (string, string, bool) {
+	name := strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	name, ok := strings.CutSuffix(name, "."+s.domain())
+	if !ok || name == "" {
+		return "", "", false
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) == 3 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") {
+		return labels[2], labels[0][1:] + "/" + labels[1][1:], true
+	}
+	return name, "", len(labels) == 1
+}
+
+func (s *Server) addresses(b *dnsmessage.Builder, name dnsmessage.Name, endpoints []Endpoint) error {
+	seen := make(map[string]bool)
+	for _, e := range endpoints {
+		ip := e.IP.To4()
+		if ip == nil || seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+
+		var a dnsmessage.AResource
+		copy(a.A[:], ip)
+		if err := b.AResource(s.header(name, dnsmessage.TypeA), a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// services answers with one SRV record per published port and the address of every
+// target in the additional section.
+func (s *Server) services(b *dnsmessage.Builder, name dnsmessage.Name, port string, endpoints []Endpoint) error {
+	var targets []Endpoint
+	for _, e := range endpoints {
+		containerPorts := make([]string, 0, len(e.Ports))
+		for p := range e.Ports {
+			if port == "" || p == port {
+				containerPorts = append(containerPorts, p)
+			}
+		}
+		if len(containerPorts) == 0 {
+			continue
+		}
+		sort.Strings(containerPorts)
+
+		target, err := dnsmessage.NewName(e.Name + "." + s.domain() + ".")
+		if err != nil {
+			return err
+		}
+		for _, p := range containerPorts {
+			srv := dnsmessage.SRVResource{Priority: 10, Weight: 10, Port: e.Ports[p], Target: target}
+			if err := b.SRVResource(s.header(name, dnsmessage.TypeSRV), srv); err != nil {
+				return err
+			}
+		}
+		targets = append(targets, e)
+	}
+
+	if err := b.StartAdditionals(); err != nil {
+		return err
+	}
+	for _, e := range targets {
+		target, _ := dnsmessage.NewName(e.Name + "." + s.domain() + ".")
+		if err := s.addresses(b, target, []Endpoint{e}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) header(name dnsmessage.Name, t dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: name, Type: t, Class: dnsmessage.ClassINET, TTL: s.ttl()}
+}
+
+// ParsePort turns a host port string from Docker into a port number, or 0.
+func ParsePort(port string) uint16 {
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return 0
+	}
+	return uint16(p)
+}
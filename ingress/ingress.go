@@ -0,0 +1,274 @@
+package ingress
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"orchard/api"
+	"orchard/dns"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RoundRobin       = "round-robin"
+	LeastConnections = "least-connections"
+)
+
+const DefaultRefreshInterval = 5 * time.Second
+
+// Rule sends requests for Host (any host if empty) under PathPrefix to the ready tasks of
+// Service, on the host port their container Port is published on. Port may be left empty
+// when the tasks publish a single port. Tasks that only listen on their container networks
+// are proxied to on their container IP, which is only reachable from the same node.
+type Rule struct {
+	Host       string
+	PathPrefix string
+	Service    string
+	Port       string
+	Strategy   string
+}
+
+type Config struct {
+	Rules          []Rule
+	RefreshSeconds int
+}
+
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Config{}, fmt.Errorf("parsing ingress config %s: %v", path, err)
+	}
+	for i, r := range c.Rules {
+		switch r.Strategy {
+		case "":
+			c.Rules[i].Strategy = RoundRobin
+		case RoundRobin, LeastConnections:
+		default:
+			return Config{}, fmt.Errorf("rule for service %s has unknown strategy %q", r.Service, r.Strategy)
+		}
+	}
+	return c, nil
+}
+
+type backend struct {
+	addr   string
+	active atomic.Int64
+}
+
+type route struct {
+	Rule
+	mu       sync.Mutex
+	backends []*backend
+	next     int
+}
+
+// Ingress is a reverse proxy that keeps its routes in sync with the manager's view of
+// each service's ready tasks.
+type Ingress struct {
+	Manager string
+	Config  Config
+
+	routes []*route
+	proxy  *httputil.ReverseProxy
+	client *http.Client
+}
+
+func New(manager string, c Config) *Ingress {
+	in := &Ingress{
+		Manager: manager,
+		Config:  c,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, r := range c.Rules {
+		in.routes = append(in.routes, &route{Rule: r})
+	}
+
+	// Longest prefixes first, so the most specific rule wins.
+	sort.SliceStable(in.routes, func(i, j int) bool {
+		return len(in.routes[i].PathPrefix) > len(in.routes[j].PathPrefix)
+	})
+
+	in.proxy = &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL.Scheme = "http"
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Error proxying %s to %s: %v\n", r.URL.Path, r.URL.Host, err)
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		},
+	}
+	return in
+}
+
+func (in *Ingress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt := in.match(r)
+	if rt == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	b := rt.pick()
+	if b == nil {
+		http.Error(w, fmt.Sprintf("no ready tasks for service %s", rt.Service), http.StatusServiceUnavailable)
+		return
+	}
+
+	b.active.Add(1)
+	defer b.active.Add(-1)
+
+	r.URL.Host = b.addr
+	in.proxy.ServeHTTP(w, r)
+}
+
+func (in *Ingress) match(r *http.Request) *route {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	for _, rt := range in.routes {
+		if rt.Host != "" && !strings.EqualFold(rt.Host, host) {
+			continue
+		}
+		if strings.HasPrefix(r.URL.Path, rt.PathPrefix) {
+			return rt
+		}
+	}
+	return nil
+}
+
+func (rt *route) pick() *backend {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if len(rt.backends) == 0 {
+		return nil
+	}
+
+	if rt.Strategy == LeastConnections {
+		best := rt.backends[rt.next%len(rt.backends)]
+		for _, b := range rt.backends {
+			if b.active.Load() < best.active.Load() {
+				best = b
+			}
+		}
+		rt.next++
+		return best
+	}
+
+	b := rt.backends[rt.next%len(rt.backends)]
+	rt.next++
+	return b
+}
+
+// update swaps in a new backend list, keeping the connection counts of backends that remain.
+func (rt *route) update(addrs []string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	existing := make(map[string]*backend, len(rt.backends))
+	for _, b := range rt.backends {
+		existing[b.addr] = b
+	}
+
+	backends := make([]*backend, 0, len(addrs))
+	for _, addr := range addrs {
+		b, ok := existing[addr]
+		if !ok {
+			b = &backend{addr: addr}
+		}
+		backends = append(backends, b)
+	}
+
+	if len(backends) != len(rt.backends) {
+		log.Printf("Ingress route %s%s -> %s: %d backends\n", rt.Host, rt.PathPrefix, rt.Service, len(backends))
+	}
+	rt.backends = backends
+}
+
+func (in *Ingress) RefreshPeriodically() {
+	interval := DefaultRefreshInterval
+	if in.Config.RefreshSeconds > 0 {
+		interval = time.Duration(in.Config.RefreshSeconds) * time.Second
+	}
+
+	in.Refresh()
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		in.Refresh()
+	}
+}
+
+// Refresh fetches the endpoints of every routed service from the manager. A service that
+// cannot be fetched keeps its previous backends.
+func (in *Ingress) Refresh() {
+	endpoints := make(map[string][]dns.Endpoint)
+	for _, rt := range in.routes {
+		eps, ok := endpoints[rt.Service]
+		if !ok {
+			var err error
+			eps, err = in.fetchEndpoints(rt.Service)
+			if err != nil {
+				log.Printf("Error fetching endpoints for service %s: %v\n", rt.Service, err)
+				continue
+			}
+			endpoints[rt.Service] = eps
+		}
+		rt.update(addresses(eps, rt.Port))
+	}
+}
+
+func (in *Ingress) fetchEndpoints(service string) ([]dns.Endpoint, error) {
+	resp, err := in.client.Get(fmt.Sprintf("http://%s/services/%s/endpoints", in.Manager, service))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	e := api.StandardResponse[[]dns.Endpoint]{}
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("manager returned %d: %s", resp.StatusCode, e.ErrorMsg)
+	}
+	return e.Response, nil
+}
+
+// addresses resolves each endpoint to the host address its container port is published on.
+func addresses(endpoints []dns.Endpoint, port string) []string {
+	if port != "" && !strings.Contains(port, "/") {
+		port += "/tcp"
+	}
+
+	var addrs []string
+	for _, e := range endpoints {
+		hostPort, ok := e.Ports[port]
+		if port == "" && len(e.Ports) > 0 {
+			ports := make([]string, 0, len(e.Ports))
+			for p := range e.Ports {
+				ports = append(ports, p)
+			}
+			sort.Strings(ports)
+			hostPort, ok = e.Ports[ports[0]], true
+		}
+		if ok {
+			addrs = append(addrs, net.JoinHostPort(e.IP.String(), fmt.Sprint(hostPort)))
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"orchard/api"
+	"orchard/dns"
 	"orchard/manager"
 	"orchard/scheduler"
 	"orchard/task"
@@ -140,6 +142,7 @@ func worker_api_spinup(addr string, port string) {
 	go w.RunTaskPeriodically()
 	go w.CollectStats()
 	go w.UpdateTasksPeriodically()
+	go w.ProbeTasksPeriodically()
 	go worker_api.StartServer()
 }
 
@@ -149,7 +152,39 @@ func main() {
 	worker_api_spinup("127.0.0.1", "7812")
 
 	workers := []string{fmt.Sprintf("%s:%s", "127.0.0.1", "7812")}
-	m := manager.New(workers, &scheduler.RoundRobin{})
+	var sched scheduler.Scheduler = &scheduler.RoundRobin{}
+	if path := os.Getenv("ORCHARD_SCHEDULER_CONFIG"); path != "" {
+		f, err := scheduler.NewFrameworkFromFile(path)
+		if err != nil {
+			log.Fatalf("Error loading scheduler config: %v\n", err)
+		}
+		sched = f
+	}
+	m := manager.New(workers, sched)
+
+	if key := os.Getenv("ORCHARD_SECRET_KEY"); key != "" {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			log.Fatalf("Error decoding ORCHARD_SECRET_KEY: %v\n", err)
+		}
+		secrets, err := manager.NewSecretStore(raw, os.Getenv("ORCHARD_SECRETS_FILE"))
+		if err != nil {
+			log.Fatalf("Error opening secret store: %v\n", err)
+		}
+		m.Secrets = secrets
+	} else {
+		log.Println("ORCHARD_SECRET_KEY not set, secrets will not survive a manager restart")
+	}
+
+	// Containers only query DNS on port 53, so ORCHARD_DNS_ADDR must listen there for them to
+	// resolve *.orchard.local. ORCHARD_DNS_ADVERTISE sets the IP containers are given for it
+	// when the manager does not run on the workers' host.
+	dnsAddr := os.Getenv("ORCHARD_DNS_ADDR")
+	if dnsAddr == "" {
+		dnsAddr = ":53"
+	}
+	m.Discovery = &dns.Server{Addr: dnsAddr, Domain: dns.DefaultDomain, Resolver: m}
+	m.DiscoveryIP = os.Getenv("ORCHARD_DNS_ADVERTISE")
 
 	manager_api := manager.HttpApiManager{
 		HttpApi: api.HttpApi[manager.Manager]{
@@ -159,6 +194,16 @@ func main() {
 		},
 	}
 
+	// Nodes report no capacity until their stats are first fetched, so fetch them before the
+	// first dispatch, giving the worker started above a moment to come up.
+	for attempt := 0; attempt < 5; attempt++ {
+		m.UpdateNodeStats()
+		if m.WorkerNodes[0].StatsHistory != nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+
 	for i := 0; i < 1; i++ {
 		te := task.TaskEvent{
 			ID:    uuid.New(),
@@ -168,10 +213,23 @@ func main() {
 
 		m.AddTask(te)
 		m.SendWork()
+		m.Deliver()
 	}
 
+	go m.UpdateNodeStatsPeriodically()
+	go m.SendWorkPeriodically()
 	go m.UpdateTasksPeriodically()
+	go m.ReconcileServicesPeriodically()
+	go m.ReconcileJobsPeriodically()
+	go m.ReconcileCronJobsPeriodically()
+	go m.ReconcileWorkflowsPeriodically()
 	go m.DoHealthChecksPeriodically()
 
+	go func() {
+		if err := m.Discovery.ListenAndServe(); err != nil {
+			log.Printf("Error starting DNS server on %s, containers will not resolve %s: %v\n", dnsAddr, dns.DefaultDomain, err)
+		}
+	}()
+
 	manager_api.StartServer()
 }
@@ -6,6 +6,8 @@ import (
 	"log"
 	"net/http"
 	"orchard/api"
+	"orchard/node"
+	"orchard/scheduler"
 	"orchard/task"
 	"time"
 
@@ -34,6 +36,10 @@ func (a *HttpApiManager) StartTaskHandler(w http.ResponseWriter, r *http.Request
 		json.NewEncoder(w).Encode(e)
 		return
 	}
+	if err := a.Ref.ValidateTask(te.Task); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	a.Ref.AddTask(te)
 	log.Printf("Added task %v\n", te.Task.ID)
 	w.WriteHeader(http.StatusCreated)
@@ -66,9 +72,7 @@ func (a *HttpApiManager) StopTaskHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	tID, _ := uuid.Parse(taskId)
-	taskToStop, ok := a.Ref.TaskDb[tID]
-
-	if !ok {
+	if _, ok := a.Ref.TaskDb[tID]; !ok {
 		w.WriteHeader(http.StatusNotFound)
 
 		json.NewEncoder(w).Encode(api.StandardResponse[any]{
@@ -77,26 +81,575 @@ func (a *HttpApiManager) StopTaskHandler(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
+	if err := a.Ref.StopTask(tID); err != nil {
+		respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+}
+
+func (a *HttpApiManager) DryRunHandler(w http.ResponseWriter, r *http.Request) {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	t := task.Task{}
+	err := d.Decode(&t)
+	if err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Print(msg)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(api.StandardResponse[any]{
+			HttpStatusCode: http.StatusBadRequest,
+			ErrorMsg:       msg,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(api.StandardResponse[scheduler.Explanation]{
+		HttpStatusCode: http.StatusOK,
+		Response:       a.Ref.DryRun(t),
+	})
+}
+
+// NodeView is a node as returned by the API. Its tasks are listed by ID only, since full tasks
+// carry their container config, environment included.
+type NodeView struct {
+	*node.Node
+	Tasks []uuid.UUID
+}
+
+func newNodeView(n *node.Node) NodeView {
+	view := NodeView{Node: n, Tasks: make([]uuid.UUID, 0, len(n.Tasks))}
+	for id := range n.Tasks {
+		view.Tasks = append(view.Tasks, id)
+	}
+	return view
+}
 
-	te := task.TaskEvent{ID: uuid.New(),
-		State:     task.Completed,
-		Timestamp: time.Now(),
+func (a *HttpApiManager) GetNodesHandler(w http.ResponseWriter, r *http.Request) {
+	nodes := make([]NodeView, 0, len(a.Ref.WorkerNodes))
+	for _, n := range a.Ref.WorkerNodes {
+		nodes = append(nodes, newNodeView(n))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(api.StandardResponse[[]NodeView]{
+		HttpStatusCode: http.StatusOK,
+		Response:       nodes,
+	})
+}
+
+func (a *HttpApiManager) SetNodeLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	n := a.Ref.getNode(vars["nodeName"])
+
+	if n == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(api.StandardResponse[any]{
+			HttpStatusCode: http.StatusNotFound,
+			ErrorMsg:       "Node not found",
+		})
+		return
+	}
+
+	labels := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&labels); err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Print(msg)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(api.StandardResponse[any]{
+			HttpStatusCode: http.StatusBadRequest,
+			ErrorMsg:       msg,
+		})
+		return
+	}
+
+	n.Labels = labels
+	log.Printf("Set labels %v on node %s\n", labels, n.Name)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(api.StandardResponse[NodeView]{
+		HttpStatusCode: http.StatusOK,
+		Response:       newNodeView(n),
+	})
+}
+
+func (a *HttpApiManager) SetNodeTaintsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	n := a.Ref.getNode(vars["nodeName"])
+
+	if n == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(api.StandardResponse[any]{
+			HttpStatusCode: http.StatusNotFound,
+			ErrorMsg:       "Node not found",
+		})
+		return
+	}
+
+	taints := []node.Taint{}
+	if err := json.NewDecoder(r.Body).Decode(&taints); err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Print(msg)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(api.StandardResponse[any]{
+			HttpStatusCode: http.StatusBadRequest,
+			ErrorMsg:       msg,
+		})
+		return
+	}
+
+	n.Taints = taints
+	log.Printf("Set taints %v on node %s\n", taints, n.Name)
+	a.Ref.EnforceTaints()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(api.StandardResponse[NodeView]{
+		HttpStatusCode: http.StatusOK,
+		Response:       newNodeView(n),
+	})
+}
+
+func respond[R any](w http.ResponseWriter, status int, response R) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(api.StandardResponse[R]{
+		HttpStatusCode: status,
+		Response:       response,
+	})
+}
+
+func respondError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(api.StandardResponse[any]{
+		HttpStatusCode: status,
+		ErrorMsg:       msg,
+	})
+}
+
+func decodeBody[R any](w http.ResponseWriter, r *http.Request, into *R) bool {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(into); err != nil {
+		msg := fmt.Sprintf("Error unmarshalling body: %v\n", err)
+		log.Print(msg)
+		respondError(w, http.StatusBadRequest, msg)
+		return false
+	}
+	return true
+}
+
+func (a *HttpApiManager) GetServicesHandler(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, a.Ref.GetServices())
+}
+
+func (a *HttpApiManager) GetServiceHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := a.Ref.ServiceDb[mux.Vars(r)["name"]]
+	if !ok {
+		respondError(w, http.StatusNotFound, "Service not found")
+		return
+	}
+	respond(w, http.StatusOK, s)
+}
+
+func (a *HttpApiManager) CreateServiceHandler(w http.ResponseWriter, r *http.Request) {
+	s := Service{}
+	if !decodeBody(w, r, &s) {
+		return
+	}
+
+	if err := a.Ref.CreateService(s); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	respond(w, http.StatusCreated, a.Ref.ServiceDb[s.Name])
+}
+
+func (a *HttpApiManager) UpdateServiceHandler(w http.ResponseWriter, r *http.Request) {
+	s := Service{}
+	if !decodeBody(w, r, &s) {
+		return
+	}
+
+	updated, err := a.Ref.UpdateService(mux.Vars(r)["name"], s)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, updated)
+}
+
+func (a *HttpApiManager) DeleteServiceHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.Ref.DeleteService(mux.Vars(r)["name"]); err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
 	}
-	taskCopy := *taskToStop
-	taskCopy.State = task.Completed
-	te.Task = taskCopy
-	a.Ref.AddTask(te)
-	log.Printf("Added task event %v to stop task %v\n", te.ID, taskToStop.ID)
 	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *HttpApiManager) RollbackServiceHandler(w http.ResponseWriter, r *http.Request) {
+	target := struct{ Revision int }{}
+	if r.ContentLength > 0 && !decodeBody(w, r, &target) {
+		return
+	}
+
+	s, err := a.Ref.RollbackService(mux.Vars(r)["name"], target.Revision)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, s)
+}
+
+func (a *HttpApiManager) PauseServiceHandler(w http.ResponseWriter, r *http.Request) {
+	s, err := a.Ref.PauseService(mux.Vars(r)["name"], true)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, s)
+}
+
+func (a *HttpApiManager) ResumeServiceHandler(w http.ResponseWriter, r *http.Request) {
+	s, err := a.Ref.PauseService(mux.Vars(r)["name"], false)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, s)
+}
+
+func (a *HttpApiManager) GetServiceEndpointsHandler(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := a.Ref.ServiceEndpoints(mux.Vars(r)["name"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, endpoints)
+}
 
+func (a *HttpApiManager) GetJobsHandler(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, a.Ref.GetJobs())
+}
+
+func (a *HttpApiManager) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	j, ok := a.Ref.JobDb[mux.Vars(r)["name"]]
+	if !ok {
+		respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	respond(w, http.StatusOK, j)
+}
+
+func (a *HttpApiManager) CreateJobHandler(w http.ResponseWriter, r *http.Request) {
+	j := Job{}
+	if !decodeBody(w, r, &j) {
+		return
+	}
+
+	if err := a.Ref.CreateJob(j); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	respond(w, http.StatusCreated, a.Ref.JobDb[j.Name])
+}
+
+func (a *HttpApiManager) DeleteJobHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.Ref.DeleteJob(mux.Vars(r)["name"]); err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *HttpApiManager) GetCronJobsHandler(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, a.Ref.GetCronJobs())
+}
+
+func (a *HttpApiManager) GetCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := a.Ref.CronJobDb[mux.Vars(r)["name"]]
+	if !ok {
+		respondError(w, http.StatusNotFound, "Cron job not found")
+		return
+	}
+	respond(w, http.StatusOK, c)
+}
+
+func (a *HttpApiManager) CreateCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	c := CronJob{}
+	if !decodeBody(w, r, &c) {
+		return
+	}
+
+	if err := a.Ref.CreateCronJob(c); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respond(w, http.StatusCreated, a.Ref.CronJobDb[c.Name])
+}
+
+func (a *HttpApiManager) DeleteCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.Ref.DeleteCronJob(mux.Vars(r)["name"]); err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *HttpApiManager) SuspendCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := a.Ref.SuspendCronJob(mux.Vars(r)["name"], true)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, c)
+}
+
+func (a *HttpApiManager) ResumeCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := a.Ref.SuspendCronJob(mux.Vars(r)["name"], false)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, c)
+}
+
+func (a *HttpApiManager) GetWorkflowsHandler(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, a.Ref.GetWorkflows())
+}
+
+func (a *HttpApiManager) GetWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	wf, ok := a.Ref.WorkflowDb[mux.Vars(r)["name"]]
+	if !ok {
+		respondError(w, http.StatusNotFound, "Workflow not found")
+		return
+	}
+	respond(w, http.StatusOK, wf)
+}
+
+func (a *HttpApiManager) GetWorkflowStepsHandler(w http.ResponseWriter, r *http.Request) {
+	steps, err := a.Ref.GetWorkflowSteps(mux.Vars(r)["name"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, steps)
+}
+
+func (a *HttpApiManager) CreateWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	wf := Workflow{}
+	if !decodeBody(w, r, &wf) {
+		return
+	}
+
+	if err := a.Ref.CreateWorkflow(wf); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respond(w, http.StatusCreated, a.Ref.WorkflowDb[wf.Name])
+}
+
+func (a *HttpApiManager) DeleteWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.Ref.DeleteWorkflow(mux.Vars(r)["name"]); err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SecretRequest creates or replaces a secret. Its value is never returned by the API.
+type SecretRequest struct {
+	Name  string
+	Value string
+}
+
+func (a *HttpApiManager) GetSecretsHandler(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, a.Ref.Secrets.List())
+}
+
+func (a *HttpApiManager) GetSecretHandler(w http.ResponseWriter, r *http.Request) {
+	info, ok := a.Ref.Secrets.Info(mux.Vars(r)["name"])
+	if !ok {
+		respondError(w, http.StatusNotFound, "Secret not found")
+		return
+	}
+	respond(w, http.StatusOK, info)
+}
+
+func (a *HttpApiManager) CreateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	req := SecretRequest{}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if _, ok := a.Ref.Secrets.Info(req.Name); ok {
+		respondError(w, http.StatusConflict, fmt.Sprintf("secret %s already exists", req.Name))
+		return
+	}
+	info, err := a.Ref.Secrets.Put(req.Name, []byte(req.Value))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respond(w, http.StatusCreated, info)
+}
+
+func (a *HttpApiManager) UpdateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	req := SecretRequest{}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if _, ok := a.Ref.Secrets.Info(name); !ok {
+		respondError(w, http.StatusNotFound, "Secret not found")
+		return
+	}
+	info, err := a.Ref.Secrets.Put(name, []byte(req.Value))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, info)
+}
+
+func (a *HttpApiManager) DeleteSecretHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, ok := a.Ref.Secrets.Info(name); !ok {
+		respondError(w, http.StatusNotFound, "Secret not found")
+		return
+	}
+	if err := a.Ref.DeleteSecret(name); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ConfigRequest creates a config or, on PUT, replaces its data.
+type ConfigRequest struct {
+	Name string
+	Data map[string]string
+}
+
+func (a *HttpApiManager) GetConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, a.Ref.GetConfigs())
+}
+
+func (a *HttpApiManager) GetConfigHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := a.Ref.ConfigDb[mux.Vars(r)["name"]]
+	if !ok {
+		respondError(w, http.StatusNotFound, "Config not found")
+		return
+	}
+	respond(w, http.StatusOK, c)
+}
+
+func (a *HttpApiManager) CreateConfigHandler(w http.ResponseWriter, r *http.Request) {
+	req := ConfigRequest{}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if err := a.Ref.CreateConfig(ConfigMap{Name: req.Name, Data: req.Data}); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respond(w, http.StatusCreated, a.Ref.ConfigDb[req.Name])
+}
+
+func (a *HttpApiManager) UpdateConfigHandler(w http.ResponseWriter, r *http.Request) {
+	req := ConfigRequest{}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	c, err := a.Ref.UpdateConfig(mux.Vars(r)["name"], req.Data)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respond(w, http.StatusOK, c)
+}
+
+func (a *HttpApiManager) DeleteConfigHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, ok := a.Ref.ConfigDb[name]; !ok {
+		respondError(w, http.StatusNotFound, "Config not found")
+		return
+	}
+	if err := a.Ref.DeleteConfig(name); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// locked serialises handlers with the manager's periodic loops.
+func (httpApi *HttpApiManager) locked(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpApi.Ref.mu.Lock()
+		defer httpApi.Ref.unlock()
+		next.ServeHTTP(w, r)
+	})
 }
 
 func (httpApi *HttpApiManager) initRouter() {
 	httpApi.Router = mux.NewRouter()
+	httpApi.Router.Use(httpApi.locked)
 
 	httpApi.Router.HandleFunc("/tasks", httpApi.GetTasksHandler).Methods("GET")
 	httpApi.Router.HandleFunc("/tasks", httpApi.StartTaskHandler).Methods("POST")
 	httpApi.Router.HandleFunc("/tasks/{taskId}", httpApi.StopTaskHandler).Methods("DELETE")
+
+	httpApi.Router.HandleFunc("/services", httpApi.GetServicesHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/services", httpApi.CreateServiceHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/services/{name}", httpApi.GetServiceHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/services/{name}", httpApi.UpdateServiceHandler).Methods("PUT")
+	httpApi.Router.HandleFunc("/services/{name}", httpApi.DeleteServiceHandler).Methods("DELETE")
+	httpApi.Router.HandleFunc("/services/{name}/endpoints", httpApi.GetServiceEndpointsHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/services/{name}/rollback", httpApi.RollbackServiceHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/services/{name}/pause", httpApi.PauseServiceHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/services/{name}/resume", httpApi.ResumeServiceHandler).Methods("POST")
+
+	httpApi.Router.HandleFunc("/jobs", httpApi.GetJobsHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/jobs", httpApi.CreateJobHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/jobs/{name}", httpApi.GetJobHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/jobs/{name}", httpApi.DeleteJobHandler).Methods("DELETE")
+
+	httpApi.Router.HandleFunc("/cronjobs", httpApi.GetCronJobsHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/cronjobs", httpApi.CreateCronJobHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/cronjobs/{name}", httpApi.GetCronJobHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/cronjobs/{name}", httpApi.DeleteCronJobHandler).Methods("DELETE")
+	httpApi.Router.HandleFunc("/cronjobs/{name}/suspend", httpApi.SuspendCronJobHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/cronjobs/{name}/resume", httpApi.ResumeCronJobHandler).Methods("POST")
+
+	httpApi.Router.HandleFunc("/workflows", httpApi.GetWorkflowsHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/workflows", httpApi.CreateWorkflowHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/workflows/{name}", httpApi.GetWorkflowHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/workflows/{name}", httpApi.DeleteWorkflowHandler).Methods("DELETE")
+	httpApi.Router.HandleFunc("/workflows/{name}/steps", httpApi.GetWorkflowStepsHandler).Methods("GET")
+
+	httpApi.Router.HandleFunc("/secrets", httpApi.GetSecretsHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/secrets", httpApi.CreateSecretHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/secrets/{name}", httpApi.GetSecretHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/secrets/{name}", httpApi.UpdateSecretHandler).Methods("PUT")
+	httpApi.Router.HandleFunc("/secrets/{name}", httpApi.DeleteSecretHandler).Methods("DELETE")
+
+	httpApi.Router.HandleFunc("/configs", httpApi.GetConfigsHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/configs", httpApi.CreateConfigHandler).Methods("POST")
+	httpApi.Router.HandleFunc("/configs/{name}", httpApi.GetConfigHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/configs/{name}", httpApi.UpdateConfigHandler).Methods("PUT")
+	httpApi.Router.HandleFunc("/configs/{name}", httpApi.DeleteConfigHandler).Methods("DELETE")
+
+	httpApi.Router.HandleFunc("/schedule/dry-run", httpApi.DryRunHandler).Methods("POST")
+
+	httpApi.Router.HandleFunc("/nodes", httpApi.GetNodesHandler).Methods("GET")
+	httpApi.Router.HandleFunc("/nodes/{nodeName}/labels", httpApi.SetNodeLabelsHandler).Methods("PUT")
+	httpApi.Router.HandleFunc("/nodes/{nodeName}/taints", httpApi.SetNodeTaintsHandler).Methods("PUT")
 }
 
 func (httpApi *HttpApiManager) StartServer() {
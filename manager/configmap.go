@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"time"
+)
+
+// ConfigMap is a named set of key/value blobs tasks can take as environment variables or files.
+type ConfigMap struct {
+	Name      string
+	Data      map[string]string
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (m *Manager) CreateConfig(c ConfigMap) error {
+	if c.Name == "" {
+		return fmt.Errorf("config name is required")
+	}
+	if _, ok := m.ConfigDb[c.Name]; ok {
+		return fmt.Errorf("config %s already exists", c.Name)
+	}
+
+	c.Version = 1
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	m.ConfigDb[c.Name] = &c
+	log.Printf("Created config %s with %d keys\n", c.Name, len(c.Data))
+	return nil
+}
+
+// UpdateConfig replaces the config's data and redeploys the tasks that asked to restart on change.
+// Services roll out a new revision instead, so their replicas are replaced at the strategy's pace.
+func (m *Manager) UpdateConfig(name string, data map[string]string) (*ConfigMap, error) {
+	c, ok := m.ConfigDb[name]
+	if !ok {
+		return nil, fmt.Errorf("config %s not found", name)
+	}
+
+	c.Data = data
+	c.Version++
+	c.UpdatedAt = time.Now()
+	log.Printf("Updated config %s to version %d\n", name, c.Version)
+
+	for _, s := range m.ServiceDb {
+		if restartsOnChange(s.Template, name) {
+			log.Printf("Service %s: rolling out config %s version %d\n", s.Name, name, c.Version)
+			s.newRevision(s.Template)
+			m.reconcileService(s)
+		}
+	}
+
+	for _, t := range m.TaskDb {
+		if t.Owner.Kind == task.OwnerService || t.State != task.Running || stopping(t) {
+			continue
+		}
+		if restartsOnChange(*t, name) {
+			log.Printf("Task %v: redeploying for config %s version %d\n", t.ID, name, c.Version)
+			m.redeployTask(t)
+		}
+	}
+	return c, nil
+}
+
+// DeleteConfig removes a config unless a live task or a template tasks are still created from references it.
+func (m *Manager) DeleteConfig(name string) error {
+	if _, ok := m.ConfigDb[name]; !ok {
+		return fmt.Errorf("config %s not found", name)
+	}
+	if user := m.templateUser(func(t task.Task) bool { return referencesConfig(t, name) }); user != "" {
+		return fmt.Errorf("config %s is in use by %s", name, user)
+	}
+	for _, t := range m.TaskDb {
+		live := t.State == task.Pending || t.State == task.Scheduled || t.State == task.Running
+		if live && referencesConfig(*t, name) {
+			return fmt.Errorf("config %s is in use by task %v", name, t.ID)
+		}
+	}
+	delete(m.ConfigDb, name)
+	log.Printf("Deleted config %s\n", name)
+	return nil
+}
+
+func (m *Manager) GetConfigs() []*ConfigMap {
+	configs := []*ConfigMap{}
+	for _, c := range m.ConfigDb {
+		configs = append(configs, c)
+	}
+	return configs
+}
+
+func referencesConfig(t task.Task, name string) bool {
+	for _, ref := range t.Configs {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func restartsOnChange(t task.Task, name string) bool {
+	for _, ref := range t.Configs {
+		if ref.Name == name && ref.RestartOnChange {
+			return true
+		}
+	}
+	return false
+}
+
+// missingConfig returns why the task cannot be placed because a config or key it references does not exist.
+func (m *Manager) missingConfig(t task.Task) string {
+	for _, ref := range t.Configs {
+		c, ok := m.ConfigDb[ref.Name]
+		if !ok {
+			return fmt.Sprintf("config %s not found", ref.Name)
+		}
+		if ref.Key == "" && ref.Env != "" {
+			return fmt.Sprintf("config %s needs a key to be set as %s", ref.Name, ref.Env)
+		}
+		if _, ok := c.Data[ref.Key]; ref.Key != "" && !ok {
+			return fmt.Sprintf("config %s has no key %s", ref.Name, ref.Key)
+		}
+	}
+	return ""
+}
+
+// withConfigs attaches the current data of the task's configs to the event sent to its worker.
+func (m *Manager) withConfigs(te task.TaskEvent) (task.TaskEvent, error) {
+	if len(te.Task.Configs) == 0 {
+		return te, nil
+	}
+
+	te.Configs = make(map[string]map[string]string, len(te.Task.Configs))
+	for _, ref := range te.Task.Configs {
+		c, ok := m.ConfigDb[ref.Name]
+		if !ok {
+			return te, fmt.Errorf("config %s not found", ref.Name)
+		}
+		te.Configs[ref.Name] = c.Data
+	}
+	return te, nil
+}
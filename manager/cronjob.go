@@ -0,0 +1,279 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/cron"
+	"time"
+)
+
+const (
+	ConcurrencyAllow   = "Allow"
+	ConcurrencyForbid  = "Forbid"
+	ConcurrencyReplace = "Replace"
+)
+
+const (
+	DefaultSuccessfulJobsHistory = 3
+	DefaultFailedJobsHistory     = 1
+)
+
+// CronRun records one job started by a cron job.
+type CronRun struct {
+	Job            string
+	ScheduledTime  time.Time
+	Phase          string
+	CompletionTime time.Time
+	Duration       time.Duration
+	Reason         string
+}
+
+type CronJobStatus struct {
+	Active           []CronRun
+	LastScheduleTime time.Time
+	NextScheduleTime time.Time
+	History          []CronRun
+	Reason           string
+}
+
+// CronJob creates a Job from JobTemplate at every activation of Schedule, evaluated in TimeZone
+// (UTC if unset). Runs more than StartingDeadlineSeconds late are skipped, and ConcurrencyPolicy
+// decides what happens when the previous run is still active. The jobs of the newest runs are
+// kept up to the history limits (the defaults if unset, while 0 keeps none) and the rest deleted.
+type CronJob struct {
+	Name                       string
+	Schedule                   string
+	TimeZone                   string
+	ConcurrencyPolicy          string
+	StartingDeadlineSeconds    int
+	SuccessfulJobsHistoryLimit *int
+	FailedJobsHistoryLimit     *int
+	Suspend                    bool
+	JobTemplate                Job
+	CreatedAt                  time.Time
+	Status                     CronJobStatus
+
+	schedule cron.Schedule
+	location *time.Location
+}
+
+func (m *Manager) CreateCronJob(c CronJob) error {
+	if c.Name == "" {
+		return fmt.Errorf("cron job name is required")
+	}
+	if _, ok := m.CronJobDb[c.Name]; ok {
+		return fmt.Errorf("cron job %s already exists", c.Name)
+	}
+	if err := c.parse(); err != nil {
+		return err
+	}
+
+	c.CreatedAt = time.Now()
+	c.Status = CronJobStatus{}
+	c.Status.NextScheduleTime = c.schedule.Next(c.CreatedAt.In(c.location))
+	m.CronJobDb[c.Name] = &c
+	log.Printf("Created cron job %s (%s %s), next run at %v\n", c.Name, c.Schedule, c.location, c.Status.NextScheduleTime)
+	return nil
+}
+
+// parse validates the schedule, time zone and policy and fills in defaults.
+func (c *CronJob) parse() error {
+	schedule, err := cron.Parse(c.Schedule)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q: %v", c.TimeZone, err)
+	}
+
+	switch c.ConcurrencyPolicy {
+	case "":
+		c.ConcurrencyPolicy = ConcurrencyAllow
+	case ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace:
+	default:
+		return fmt.Errorf("unknown concurrency policy %q", c.ConcurrencyPolicy)
+	}
+	if c.SuccessfulJobsHistoryLimit == nil {
+		limit := DefaultSuccessfulJobsHistory
+		c.SuccessfulJobsHistoryLimit = &limit
+	} else if *c.SuccessfulJobsHistoryLimit < 0 {
+		return fmt.Errorf("cron job %s: successful jobs history limit cannot be negative", c.Name)
+	}
+	if c.FailedJobsHistoryLimit == nil {
+		limit := DefaultFailedJobsHistory
+		c.FailedJobsHistoryLimit = &limit
+	} else if *c.FailedJobsHistoryLimit < 0 {
+		return fmt.Errorf("cron job %s: failed jobs history limit cannot be negative", c.Name)
+	}
+
+	c.schedule = schedule
+	c.location = location
+	return nil
+}
+
+// DeleteCronJob removes the cron job along with every job it started.
+func (m *Manager) DeleteCronJob(name string) error {
+	c, ok := m.CronJobDb[name]
+	if !ok {
+		return fmt.Errorf("cron job %s not found", name)
+	}
+
+	for _, run := range c.Status.History {
+		m.DeleteJob(run.Job)
+	}
+	for _, run := range c.Status.Active {
+		m.DeleteJob(run.Job)
+	}
+	delete(m.CronJobDb, name)
+	log.Printf("Deleted cron job %s\n", name)
+	return nil
+}
+
+func (m *Manager) SuspendCronJob(name string, suspend bool) (*CronJob, error) {
+	c, ok := m.CronJobDb[name]
+	if !ok {
+		return nil, fmt.Errorf("cron job %s not found", name)
+	}
+
+	c.Suspend = suspend
+	if !suspend {
+		// Runs missed while suspended are not caught up on.
+		c.Status.LastScheduleTime = time.Now()
+		c.Status.NextScheduleTime = c.schedule.Next(time.Now().In(c.location))
+	}
+	return c, nil
+}
+
+func (m *Manager) GetCronJobs() []*CronJob {
+	cronJobs := []*CronJob{}
+	for _, c := range m.CronJobDb {
+		cronJobs = append(cronJobs, c)
+	}
+	return cronJobs
+}
+
+func (m *Manager) ReconcileCronJobsPeriodically() {
+	ticker := time.NewTicker(time.Second * 10)
+	for range ticker.C {
+		m.mu.Lock()
+		m.ReconcileCronJobs()
+		m.unlock()
+	}
+}
+
+func (m *Manager) ReconcileCronJobs() {
+	for _, c := range m.CronJobDb {
+		m.reconcileCronJob(c, time.Now())
+	}
+}
+
+func (m *Manager) reconcileCronJob(c *CronJob, now time.Time) {
+	m.collectCronRuns(c)
+	if c.Suspend {
+		return
+	}
+
+	scheduled, missed := c.lastMissed(now.In(c.location))
+	c.Status.NextScheduleTime = c.schedule.Next(now.In(c.location))
+	if scheduled.IsZero() {
+		return
+	}
+	c.Status.LastScheduleTime = scheduled
+	if missed > 1 {
+		log.Printf("Cron job %s: missed %d runs, only starting the latest\n", c.Name, missed-1)
+	}
+
+	deadline := time.Duration(c.StartingDeadlineSeconds) * time.Second
+	if deadline > 0 && now.Sub(scheduled) > deadline {
+		c.Status.Reason = fmt.Sprintf("run scheduled for %v missed its starting deadline", scheduled)
+		log.Printf("Cron job %s: %s\n", c.Name, c.Status.Reason)
+		return
+	}
+
+	if len(c.Status.Active) > 0 {
+		switch c.ConcurrencyPolicy {
+		case ConcurrencyForbid:
+			c.Status.Reason = fmt.Sprintf("run scheduled for %v skipped, previous run still active", scheduled)
+			log.Printf("Cron job %s: %s\n", c.Name, c.Status.Reason)
+			return
+		case ConcurrencyReplace:
+			for _, run := range c.Status.Active {
+				log.Printf("Cron job %s: replacing active job %s\n", c.Name, run.Job)
+				m.DeleteJob(run.Job)
+			}
+			c.Status.Active = nil
+		}
+	}
+
+	j := c.JobTemplate
+	j.Name = fmt.Sprintf("%s-%d", c.Name, scheduled.Unix())
+	if err := m.CreateJob(j); err != nil {
+		c.Status.Reason = err.Error()
+		log.Printf("Cron job %s: unable to start job: %v\n", c.Name, err)
+		return
+	}
+	c.Status.Active = append(c.Status.Active, CronRun{Job: j.Name, ScheduledTime: scheduled, Phase: JobActive})
+	c.Status.Reason = ""
+}
+
+// lastMissed returns the most recent activation at or before now that has not run yet,
+// and how many activations have passed since the last run.
+func (c *CronJob) lastMissed(now time.Time) (time.Time, int) {
+	from := c.Status.LastScheduleTime
+	if from.IsZero() {
+		from = c.CreatedAt
+	}
+
+	var last time.Time
+	missed := 0
+	for t := c.schedule.Next(from.In(c.location)); !t.IsZero() && !t.After(now); t = c.schedule.Next(t) {
+		last = t
+		missed++
+	}
+	return last, missed
+}
+
+// collectCronRuns moves finished jobs into the run history and deletes the oldest runs
+// beyond the history limits.
+func (m *Manager) collectCronRuns(c *CronJob) {
+	var active []CronRun
+	for _, run := range c.Status.Active {
+		j, ok := m.JobDb[run.Job]
+		if !ok {
+			continue
+		}
+		if j.Status.Phase == JobActive {
+			active = append(active, run)
+			continue
+		}
+
+		run.Phase = j.Status.Phase
+		run.CompletionTime = j.Status.CompletionTime
+		run.Duration = j.Status.Duration
+		run.Reason = j.Status.Reason
+		c.Status.History = append(c.Status.History, run)
+	}
+	c.Status.Active = active
+
+	succeeded, failed := 0, 0
+	var history []CronRun
+	for i := len(c.Status.History) - 1; i >= 0; i-- {
+		run := c.Status.History[i]
+		keep := false
+		if run.Phase == JobSucceeded {
+			succeeded++
+			keep = succeeded <= *c.SuccessfulJobsHistoryLimit
+		} else {
+			failed++
+			keep = failed <= *c.FailedJobsHistoryLimit
+		}
+
+		if keep {
+			history = append([]CronRun{run}, history...)
+		} else if _, ok := m.JobDb[run.Job]; ok {
+			m.DeleteJob(run.Job)
+		}
+	}
+	c.Status.History = history
+}
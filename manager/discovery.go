@@ -0,0 +1,179 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"orchard/dns"
+	"orchard/task"
+	"strings"
+)
+
+// discoverySnapshot holds the ready endpoints of every service and task name, keyed in lower
+// case. Resolve answers from it so lookups never wait on the manager's lock.
+type discoverySnapshot struct {
+	services map[string][]dns.Endpoint
+	tasks    map[string][]dns.Endpoint
+}
+
+// Resolve answers service discovery lookups from the latest snapshot: a service name resolves
+// to the ready replicas the service owns and any other name to the ready task of that name.
+func (m *Manager) Resolve(name string) ([]dns.Endpoint, bool) {
+	snap := m.discovery.Load()
+	if snap == nil {
+		return nil, false
+	}
+
+	name = strings.ToLower(name)
+	if endpoints, ok := snap.services[name]; ok {
+		return endpoints, true
+	}
+	endpoints, ok := snap.tasks[name]
+	return endpoints, ok
+}
+
+// snapshotDiscovery publishes the current endpoints for Resolve. It is called with the lock held.
+func (m *Manager) snapshotDiscovery() {
+	snap := &discoverySnapshot{
+		services: make(map[string][]dns.Endpoint, len(m.ServiceDb)),
+		tasks:    make(map[string][]dns.Endpoint, len(m.TaskDb)),
+	}
+	for service := range m.ServiceDb {
+		snap.services[strings.ToLower(service)] = nil
+	}
+	for _, t := range m.TaskDb {
+		name := strings.ToLower(t.Name)
+		endpoints := snap.tasks[name]
+		if e, ok := m.readyEndpoint(t); ok {
+			endpoints = append(endpoints, e)
+			owner := strings.ToLower(t.Owner.Name)
+			if _, ok := snap.services[owner]; ok && t.Owner.Kind == task.OwnerService {
+				snap.services[owner] = append(snap.services[owner], e)
+			}
+		}
+		snap.tasks[name] = endpoints
+	}
+	m.discovery.Store(snap)
+}
+
+// ServiceEndpoints returns the ready replicas of a service, for proxies routing to it.
+func (m *Manager) ServiceEndpoints(name string) ([]dns.Endpoint, error) {
+	if _, ok := m.ServiceDb[name]; !ok {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	endpoints := m.readyEndpoints(func(t *task.Task) bool {
+		return t.Owner.Kind == task.OwnerService && t.Owner.Name == name
+	})
+	if endpoints == nil {
+		endpoints = []dns.Endpoint{}
+	}
+	return endpoints, nil
+}
+
+func (m *Manager) readyEndpoints(match func(t *task.Task) bool) []dns.Endpoint {
+	var endpoints []dns.Endpoint
+	for _, t := range m.TaskDb {
+		if !match(t) {
+			continue
+		}
+		if e, ok := m.readyEndpoint(t); ok {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// readyEndpoint locates a task that is running, ready and not being stopped.
+func (m *Manager) readyEndpoint(t *task.Task) (dns.Endpoint, bool) {
+	if t.State != task.Running || !t.Ready || stopping(t) {
+		return dns.Endpoint{}, false
+	}
+	return m.endpoint(t)
+}
+
+// endpoint locates a task at its node's address and the host ports it publishes. A task that
+// publishes no ports but runs on a container network is located at its container IP and
+// container ports instead, which other containers on the node can reach.
+func (m *Manager) endpoint(t *task.Task) (dns.Endpoint, bool) {
+	if e, ok := containerEndpoint(t); ok {
+		return e, true
+	}
+
+	host, _, err := net.SplitHostPort(m.TaskWorkerMap[t.ID])
+	if err != nil {
+		return dns.Endpoint{}, false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ips, err := net.LookupIP(host)
+		if err != nil || len(ips) == 0 {
+			return dns.Endpoint{}, false
+		}
+		ip = ips[0]
+	}
+
+	e := dns.Endpoint{Name: t.Name, IP: ip, Ports: make(map[string]uint16)}
+	for port, bindings := range t.HostPorts {
+		if len(bindings) == 0 {
+			continue
+		}
+		if hostPort := dns.ParsePort(bindings[0].HostPort); hostPort != 0 {
+			e.Ports[string(port)] = hostPort
+		}
+	}
+	return e, true
+}
+
+func containerEndpoint(t *task.Task) (dns.Endpoint, bool) {
+	ip := net.ParseIP(t.ContainerIP)
+	if ip == nil || (len(t.Network.Networks) == 0 && !t.Network.PublishListedOnly) {
+		return dns.Endpoint{}, false
+	}
+	for _, bindings := range t.HostPorts {
+		if len(bindings) > 0 && bindings[0].HostPort != "" {
+			return dns.Endpoint{}, false
+		}
+	}
+
+	e := dns.Endpoint{Name: t.Name, IP: ip, Ports: make(map[string]uint16)}
+	for port := range t.HostPorts {
+		if p := dns.ParsePort(port.Port()); p != 0 {
+			e.Ports[string(port)] = p
+		}
+	}
+	return e, true
+}
+
+// DockerBridgeGateway is the node's address on Docker's default bridge. Containers on any bridge
+// network of the node can reach a DNS server listening on all of the node's interfaces there.
+const DockerBridgeGateway = "172.17.0.1"
+
+// discoveryConfig points the task's containers at the discovery DNS server. Container resolvers
+// only query port 53, so the server is only handed out when it listens there. Containers get
+// DiscoveryIP when set, else the server's listen address; a server listening on all interfaces
+// is reached through the bridge gateway, which assumes the manager runs on the task's node.
+// A loopback listen address is never reachable from a container.
+func (m *Manager) discoveryConfig(t *task.Task) {
+	if m.Discovery == nil {
+		return
+	}
+
+	t.TaskConfig.DnsSearch = []string{m.Discovery.Domain}
+	host, port, err := net.SplitHostPort(m.Discovery.Addr)
+	if err != nil || port != "53" {
+		return
+	}
+
+	ip := net.ParseIP(m.DiscoveryIP)
+	if ip == nil && host == "" {
+		ip = net.IPv4zero
+	} else if ip == nil {
+		ip = net.ParseIP(host)
+	}
+	if ip != nil && ip.IsUnspecified() {
+		ip = net.ParseIP(DockerBridgeGateway)
+	}
+	if ip != nil && !ip.IsLoopback() {
+		t.TaskConfig.Dns = []string{ip.String()}
+	}
+}
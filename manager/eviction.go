@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/node"
+	"orchard/scheduler"
+	"orchard/task"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnforceTaints evicts every task running on a node with a NoExecute taint it does not tolerate.
+func (m *Manager) EnforceTaints() {
+	for _, n := range m.WorkerNodes {
+		for _, t := range n.Tasks {
+			untolerated := n.UntoleratedTaints(t, node.NoExecute)
+			if len(untolerated) == 0 {
+				continue
+			}
+			m.evictTask(n, t.ID, fmt.Sprintf("evicted: untolerated taint %s", untolerated[0]))
+		}
+	}
+}
+
+// preempt evicts lower-priority tasks so that t can be placed on the next pass. It reports whether any were evicted.
+func (m *Manager) preempt(t task.Task) bool {
+	p := scheduler.SelectVictims(m.Scheduler, t, m.WorkerNodes)
+	if p == nil {
+		return false
+	}
+
+	for _, victim := range p.Victims {
+		m.evictTask(p.Node, victim.ID, fmt.Sprintf("preempted by task %v (priority %d)", t.ID, t.Priority))
+	}
+	return true
+}
+
+// evictTask gracefully stops the task on its node, records the eviction as a task event
+// and queues a replacement with a fresh ID for rescheduling when nothing else will.
+func (m *Manager) evictTask(n *node.Node, taskId uuid.UUID, reason string) {
+	t, ok := m.TaskDb[taskId]
+	if !ok {
+		return
+	}
+
+	log.Printf("Evicting task %v from node %s: %s\n", taskId, n.Name, reason)
+	if err := m.stopTask(n.Name, taskId.String()); err != nil {
+		log.Printf("Unable to evict task %v: %v\n", taskId, err)
+		return
+	}
+	t.Event = task.SpinDown
+	n.Release(*t)
+	t.Reason = reason
+
+	evicted := task.TaskEvent{
+		ID:        uuid.New(),
+		State:     task.Completed,
+		Timestamp: time.Now(),
+		Task:      *t,
+		Reason:    reason,
+	}
+	m.EventDb[evicted.ID] = &evicted
+
+	// Owned tasks are replaced by their service, job or workflow, and a gang member can only
+	// be placed together with its gang.
+	if t.Owner.Kind != "" || t.Gang.Name != "" {
+		return
+	}
+
+	replacement := *t
+	replacement.ID = uuid.New()
+	replacement.Event = task.SpinUp
+	replacement.ContainerId = ""
+	replacement.HostPorts = nil
+	if len(replacement.Ports) > 0 {
+		replacement.PortBindings = nil
+	}
+	replacement.RestartCount = 0
+	replacement.Reason = ""
+	m.submit(replacement)
+}
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/node"
+	"orchard/task"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const DefaultGangTimeout = 5 * time.Minute
+
+type Gang struct {
+	Name       string
+	Size       int
+	Timeout    time.Duration
+	CreatedAt  time.Time
+	Members    map[uuid.UUID]*task.TaskEvent
+	Reserved   map[uuid.UUID]*node.Node
+	Dispatched map[uuid.UUID]bool
+
+	// sending holds the members dispatched but not yet delivered.
+	sending map[uuid.UUID]bool
+}
+
+// ValidateGang rejects gang specs that could never be scheduled.
+func ValidateGang(spec task.GangSpec) error {
+	if spec.Name != "" && spec.Size < 1 {
+		return fmt.Errorf("gang %s must have a size of at least 1, got %d", spec.Name, spec.Size)
+	}
+	return nil
+}
+
+func (m *Manager) addGangMember(te task.TaskEvent) {
+	spec := te.Task.Gang
+	if err := ValidateGang(spec); err != nil {
+		log.Printf("Task %v cannot be placed: %v\n", te.Task.ID, err)
+		te.Task.State = task.Failed
+		te.Task.Reason = err.Error()
+		m.TaskDb[te.Task.ID] = &te.Task
+		return
+	}
+
+	g, ok := m.Gangs[spec.Name]
+	if !ok {
+		timeout := time.Duration(spec.TimeoutSeconds) * time.Second
+		if timeout == 0 {
+			timeout = DefaultGangTimeout
+		}
+		g = &Gang{
+			Name:       spec.Name,
+			Size:       spec.Size,
+			Timeout:    timeout,
+			CreatedAt:  time.Now(),
+			Members:    make(map[uuid.UUID]*task.TaskEvent),
+			Reserved:   make(map[uuid.UUID]*node.Node),
+			Dispatched: make(map[uuid.UUID]bool),
+			sending:    make(map[uuid.UUID]bool),
+		}
+		m.Gangs[spec.Name] = g
+	}
+
+	te.Task.State = task.Pending
+	g.Members[te.Task.ID] = &te
+	m.TaskDb[te.Task.ID] = &te.Task
+	log.Printf("Task %v joined gang %s (%d/%d)\n", te.Task.ID, g.Name, len(g.Members), g.Size)
+
+	m.scheduleGang(g)
+}
+
+func (m *Manager) ScheduleGangs() {
+	for _, g := range m.Gangs {
+		m.scheduleGang(g)
+	}
+}
+
+// scheduleGang moves a gang on: reserve capacity for every member at once, then dispatch them,
+// giving up and releasing every reservation once the gang's timeout passes.
+func (m *Manager) scheduleGang(g *Gang) {
+	if len(g.sending) > 0 {
+		return
+	}
+	if time.Since(g.CreatedAt) > g.Timeout {
+		m.abortGang(g, fmt.Sprintf("gang %s timed out after %v", g.Name, g.Timeout))
+		return
+	}
+
+	if len(g.Members) < g.Size {
+		m.setGangReason(g, fmt.Sprintf("waiting for gang members %d/%d", len(g.Members), g.Size))
+		return
+	}
+
+	if len(g.Reserved) == 0 {
+		if err := m.reserveGang(g); err != nil {
+			m.setGangReason(g, err.Error())
+			return
+		}
+	}
+
+	for id, te := range g.Members {
+		if g.Dispatched[id] {
+			continue
+		}
+		g.sending[id] = true
+		m.dispatch(*te, g.Reserved[id], func(err error) {
+			delete(g.sending, id)
+			if err == nil {
+				g.Dispatched[id] = true
+			}
+			if len(g.Dispatched) == len(g.Members) {
+				log.Printf("Gang %s fully dispatched\n", g.Name)
+				delete(m.Gangs, g.Name)
+			}
+		})
+	}
+}
+
+// reserveGang places members one at a time so each sees the capacity taken by the previous ones,
+// rolling back every reservation if any member does not fit.
+func (m *Manager) reserveGang(g *Gang) error {
+	for id, te := range g.Members {
+		w, err := m.SelectWorker(te.Task)
+		if err == nil {
+			err = m.assign(te, w)
+		}
+		if err != nil {
+			for reservedId, n := range g.Reserved {
+				m.unassign(g.Members[reservedId], n)
+			}
+			g.Reserved = make(map[uuid.UUID]*node.Node)
+			return fmt.Errorf("gang %s: %v", g.Name, err)
+		}
+		g.Reserved[id] = w
+	}
+
+	log.Printf("Reserved capacity for all %d members of gang %s\n", len(g.Members), g.Name)
+	return nil
+}
+
+func (m *Manager) abortGang(g *Gang, reason string) {
+	log.Printf("Aborting gang %s: %s\n", g.Name, reason)
+
+	for id, te := range g.Members {
+		if n, ok := g.Reserved[id]; ok {
+			if g.Dispatched[id] {
+				m.stopTask(n.Name, id.String())
+			}
+			m.unassign(te, n)
+		}
+		te.Task.State = task.Failed
+		te.Task.Reason = reason
+		m.TaskDb[id] = &te.Task
+	}
+
+	delete(m.Gangs, g.Name)
+}
+
+func (m *Manager) setGangReason(g *Gang, reason string) {
+	for _, te := range g.Members {
+		te.Task.Reason = reason
+	}
+}
@@ -0,0 +1,207 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	JobActive    = "Active"
+	JobSucceeded = "Succeeded"
+	JobFailed    = "Failed"
+)
+
+const (
+	DefaultBackoffLimit = 6
+	JobBackoffBase      = 10 * time.Second
+	JobBackoffMax       = 6 * time.Minute
+)
+
+type JobStatus struct {
+	Phase          string
+	Active         int
+	Succeeded      int
+	Failed         int
+	StartTime      time.Time
+	CompletionTime time.Time
+	Duration       time.Duration
+	NextRetry      time.Time
+	Reason         string
+}
+
+// Job runs its template until Completions tasks exit successfully, with at most Parallelism
+// running at once, giving up after more than BackoffLimit failures (DefaultBackoffLimit if unset,
+// while 0 gives up on the first failure).
+type Job struct {
+	Name         string
+	Template     task.Task
+	Completions  int
+	Parallelism  int
+	BackoffLimit *int
+	CreatedAt    time.Time
+	Status       JobStatus
+}
+
+func (m *Manager) CreateJob(j Job) error {
+	if j.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if _, ok := m.JobDb[j.Name]; ok {
+		return fmt.Errorf("job %s already exists", j.Name)
+	}
+
+	if j.Completions <= 0 {
+		j.Completions = 1
+	}
+	if j.Parallelism <= 0 {
+		j.Parallelism = 1
+	}
+	if j.BackoffLimit == nil {
+		limit := DefaultBackoffLimit
+		j.BackoffLimit = &limit
+	} else if *j.BackoffLimit < 0 {
+		return fmt.Errorf("job %s: backoff limit cannot be negative", j.Name)
+	}
+
+	j.CreatedAt = time.Now()
+	j.Status = JobStatus{Phase: JobActive, StartTime: j.CreatedAt}
+	m.JobDb[j.Name] = &j
+	log.Printf("Created job %s for %d completions\n", j.Name, j.Completions)
+	m.reconcileJob(&j)
+	return nil
+}
+
+func (m *Manager) DeleteJob(name string) error {
+	j, ok := m.JobDb[name]
+	if !ok {
+		return fmt.Errorf("job %s not found", name)
+	}
+
+	for _, t := range m.jobTasks(j) {
+		if t.State == task.Pending || t.State == task.Scheduled || t.State == task.Running {
+			m.StopTask(t.ID)
+		}
+	}
+	delete(m.JobDb, name)
+	log.Printf("Deleted job %s\n", name)
+	return nil
+}
+
+func (m *Manager) GetJobs() []*Job {
+	jobs := []*Job{}
+	for _, j := range m.JobDb {
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+func (m *Manager) ReconcileJobsPeriodically() {
+	ticker := time.NewTicker(time.Second * 5)
+	for range ticker.C {
+		m.mu.Lock()
+		m.ReconcileJobs()
+		m.unlock()
+	}
+}
+
+func (m *Manager) ReconcileJobs() {
+	for _, j := range m.JobDb {
+		m.reconcileJob(j)
+	}
+}
+
+func (m *Manager) reconcileJob(j *Job) {
+	if j.Status.Phase != JobActive {
+		return
+	}
+
+	var active []*task.Task
+	succeeded, failed := 0, 0
+	for _, t := range m.jobTasks(j) {
+		switch t.State {
+		case task.Pending, task.Scheduled, task.Running:
+			active = append(active, t)
+		case task.Completed:
+			if !stopping(t) {
+				succeeded++
+			}
+		case task.Failed:
+			failed++
+		}
+	}
+
+	if failed > j.Status.Failed {
+		j.Status.NextRetry = time.Now().Add(jobBackoff(failed))
+		log.Printf("Job %s: %d failures, next retry at %v\n", j.Name, failed, j.Status.NextRetry)
+	}
+	j.Status.Active = len(active)
+	j.Status.Succeeded = succeeded
+	j.Status.Failed = failed
+
+	switch {
+	case failed > *j.BackoffLimit:
+		m.finishJob(j, JobFailed, fmt.Sprintf("backoff limit %d exceeded", *j.BackoffLimit), active)
+		return
+	case succeeded >= j.Completions:
+		m.finishJob(j, JobSucceeded, "", active)
+		return
+	}
+
+	if time.Now().Before(j.Status.NextRetry) {
+		return
+	}
+
+	want := j.Parallelism
+	if remaining := j.Completions - succeeded; remaining < want {
+		want = remaining
+	}
+	for i := len(active); i < want; i++ {
+		t := newJobTask(j)
+		log.Printf("Job %s: starting task %v\n", j.Name, t.ID)
+		m.submit(t)
+	}
+}
+
+func (m *Manager) finishJob(j *Job, phase string, reason string, active []*task.Task) {
+	for _, t := range active {
+		m.StopTask(t.ID)
+	}
+
+	j.Status.Phase = phase
+	j.Status.Reason = reason
+	j.Status.Active = 0
+	j.Status.CompletionTime = time.Now()
+	j.Status.Duration = j.Status.CompletionTime.Sub(j.Status.StartTime)
+	log.Printf("Job %s %s after %v %s\n", j.Name, phase, j.Status.Duration, reason)
+}
+
+func (m *Manager) jobTasks(j *Job) []*task.Task {
+	var tasks []*task.Task
+	for _, t := range m.TaskDb {
+		if t.Owner.Kind == task.OwnerJob && t.Owner.Name == j.Name {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+func jobBackoff(failures int) time.Duration {
+	return backoff(JobBackoffBase, JobBackoffMax, failures)
+}
+
+func newJobTask(j *Job) task.Task {
+	t := j.Template
+	t.ID = uuid.New()
+	t.Name = fmt.Sprintf("%s-%s", j.Name, t.ID.String()[:8])
+	t.Owner = task.Owner{Kind: task.OwnerJob, Name: j.Name}
+	t.TaskConfig.Name = t.Name
+	t.ContainerId = ""
+	t.HostPorts = nil
+	t.RestartCount = 0
+	t.Reason = ""
+	return t
+}
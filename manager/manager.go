@@ -8,19 +8,19 @@ import (
 	"log"
 	"net/http"
 	"orchard/api"
+	"orchard/dns"
 	"orchard/node"
 	"orchard/scheduler"
 	"orchard/task"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/docker/go-connections/nat"
-	"github.com/golang-collections/collections/queue"
 	"github.com/google/uuid"
 )
 
 type Manager struct {
-	Pending       queue.Queue
+	Pending       PendingQueue
 	TaskDb        map[uuid.UUID]*task.Task
 	EventDb       map[uuid.UUID]*task.TaskEvent
 	Workers       []string
@@ -30,30 +30,168 @@ type Manager struct {
 	LastWorker    int
 	WorkerNodes   []*node.Node
 	Scheduler     scheduler.Scheduler
+	Gangs         map[string]*Gang
+	Ports         PortAllocator
+	ServiceDb     map[string]*Service
+	JobDb         map[string]*Job
+	CronJobDb     map[string]*CronJob
+	WorkflowDb    map[string]*Workflow
+	Discovery     *dns.Server
+	DiscoveryIP   string
+	Secrets       *SecretStore
+	ConfigDb      map[string]*ConfigMap
+
+	// mu guards the manager's state. The periodic loops and the API handlers hold it,
+	// so the methods they call assume it is held.
+	mu sync.Mutex
+	// discovery is the snapshot DNS lookups answer from, refreshed whenever mu is released.
+	discovery atomic.Pointer[discoverySnapshot]
+	// outbox holds the task events dispatched under mu, for Deliver to post without it.
+	outbox []delivery
+}
+
+// delivery is a task event bound for a worker, prepared under the lock and posted outside it.
+// done records the outcome and is called with the lock held.
+type delivery struct {
+	te   task.TaskEvent
+	w    *node.Node
+	data []byte
+	done func(err error)
+}
+
+// unlock refreshes the discovery snapshot from the state changed under the lock and releases it.
+func (m *Manager) unlock() {
+	m.snapshotDiscovery()
+	m.mu.Unlock()
 }
 
 func (m *Manager) AddTask(te task.TaskEvent) {
 	m.Pending.Enqueue(te)
 }
 
+// submit records a new task as Pending and queues it for scheduling.
+func (m *Manager) submit(t task.Task) {
+	t.State = task.Pending
+	m.TaskDb[t.ID] = &t
+	m.AddTask(task.TaskEvent{
+		ID:        uuid.New(),
+		State:     task.Pending,
+		Timestamp: time.Now(),
+		Task:      t,
+	})
+}
+
+// StopTask stops a placed task on its worker, or cancels one that is still waiting to be placed.
+// Stopped tasks are marked with the SpinDown event so they are neither counted as live nor restarted.
+// If the worker cannot be reached the task is left unmarked, so callers retry on their next pass.
+func (m *Manager) StopTask(id uuid.UUID) error {
+	t, ok := m.TaskDb[id]
+	if !ok {
+		return fmt.Errorf("task %v not found", id)
+	}
+
+	if stopping(t) || t.State == task.Completed || t.State == task.Failed {
+		return nil
+	}
+
+	w, placed := m.TaskWorkerMap[id]
+	if !placed {
+		t.Event = task.SpinDown
+		t.State = task.Completed
+		t.Reason = "cancelled before placement"
+		return nil
+	}
+
+	if err := m.stopTask(w, id.String()); err != nil {
+		return fmt.Errorf("stopping task %v on %s: %v", id, w, err)
+	}
+	t.Event = task.SpinDown
+	log.Printf("Requested stop of task %v on %s\n", id, w)
+	return nil
+}
+
+// stopping reports whether the task was stopped through StopTask or evicted, rather than exiting on its own.
+func stopping(t *task.Task) bool {
+	return t.Event == task.SpinDown
+}
+
+// templateUser names the service, active job, cron job or running workflow whose template
+// matches uses, as they will still create tasks from it, or returns "".
+func (m *Manager) templateUser(uses func(t task.Task) bool) string {
+	for _, s := range m.ServiceDb {
+		if uses(s.Template) {
+			return "service " + s.Name
+		}
+	}
+	for _, j := range m.JobDb {
+		if j.Status.Phase == JobActive && uses(j.Template) {
+			return "job " + j.Name
+		}
+	}
+	for _, c := range m.CronJobDb {
+		if uses(c.JobTemplate.Template) {
+			return "cron job " + c.Name
+		}
+	}
+	for _, wf := range m.WorkflowDb {
+		if wf.Status.Phase != WorkflowRunning {
+			continue
+		}
+		for _, step := range wf.Steps {
+			if uses(step.Template) {
+				return "workflow " + wf.Name
+			}
+		}
+	}
+	return ""
+}
+
 func (m *Manager) SelectWorker(t task.Task) (*node.Node, error) {
 	candidateNodes := m.Scheduler.SelectCandidateNodes(t, m.WorkerNodes)
-	if candidateNodes == nil {
-		msg := fmt.Sprintf("No available candidates match resource request for task %v", t.ID)
-		err := errors.New(msg)
-		return nil, err
+	if len(candidateNodes) == 0 {
+		return nil, errors.New(scheduler.UnschedulableReason(m.Scheduler, t, m.WorkerNodes))
 	}
-	scores := m.Scheduler.ScoreNodes(t, candidateNodes)
-	return m.Scheduler.PickNode(scores, candidateNodes), nil
+	scores := m.Scheduler.ScoreNodes(t, candidateNodes, m.WorkerNodes)
+	picked := m.Scheduler.PickNode(scores, candidateNodes)
+
+	if binder, ok := m.Scheduler.(scheduler.Binder); ok {
+		if err := binder.Bind(t, picked); err != nil {
+			return nil, err
+		}
+	}
+	return picked, nil
+}
+
+// DryRun explains where t would be placed without placing it.
+func (m *Manager) DryRun(t task.Task) scheduler.Explanation {
+	return scheduler.Explain(m.Scheduler, t, m.WorkerNodes)
 }
 
+// SendWork makes one pass over the pending queue, highest priority first, trying every queued
+// event once so that tasks which cannot be placed yet do not hold up the ones behind them.
+// Once a task has preempted others, lower priority tasks wait for the next pass so they do
+// not take the capacity being freed for it.
 func (m *Manager) SendWork() {
-	if m.Pending.Len() == 0 {
-		return
+	queued := m.Pending
+	m.Pending = PendingQueue{}
+
+	preempting, preemptor := false, 0
+	for queued.Len() > 0 {
+		te := queued.Dequeue()
+		if preempting && te.Task.Priority < preemptor {
+			m.Pending.Enqueue(te)
+			continue
+		}
+		if m.sendEvent(te) {
+			preempting, preemptor = true, te.Task.Priority
+		}
 	}
+}
 
-	te := m.Pending.Dequeue().(task.TaskEvent)
-	log.Printf("Pulled %v off pending queue\n", te.Task)
+// sendEvent places a single pending event, queueing it again when it cannot be placed yet.
+// It reports whether placing it preempted lower priority tasks.
+func (m *Manager) sendEvent(te task.TaskEvent) bool {
+	log.Printf("Pulled task %v off pending queue\n", te.Task.ID)
 	m.EventDb[te.ID] = &te
 
 	taskWorker, ok := m.TaskWorkerMap[te.Task.ID]
@@ -61,51 +199,173 @@ func (m *Manager) SendWork() {
 		persistedTask := m.TaskDb[te.Task.ID]
 		if te.State == task.Completed && task.TaskFSM.ValidStateTransition(persistedTask.State, te.State) {
 			m.stopTask(taskWorker, te.Task.ID.String())
-			return
+			return false
 		}
 		log.Printf("invalid request: existing task %s is in state %v and cannot transition to the completed state\n", persistedTask.ID.String(), persistedTask.State)
-		return
+		return false
+	}
+
+	if persisted, ok := m.TaskDb[te.Task.ID]; ok && persisted.State == task.Completed {
+		log.Printf("Task %v was cancelled before placement\n", te.Task.ID)
+		return false
+	}
+
+	if reason := m.waitingFor(te.Task); reason != "" {
+		log.Printf("Task %v is %s\n", te.Task.ID, reason)
+		te.Task.State = task.Pending
+		te.Task.Reason = reason
+		m.TaskDb[te.Task.ID] = &te.Task
+		m.Pending.Enqueue(te)
+		return false
+	}
+
+	reason := m.missingSecret(te.Task)
+	if reason == "" {
+		reason = m.missingConfig(te.Task)
+	}
+	if reason != "" {
+		log.Printf("Task %v cannot be placed: %s\n", te.Task.ID, reason)
+		te.Task.State = task.Pending
+		te.Task.Reason = reason
+		m.TaskDb[te.Task.ID] = &te.Task
+		m.Pending.Enqueue(te)
+		return false
+	}
+
+	if te.Task.Gang.Name != "" {
+		m.addGangMember(te)
+		return false
 	}
 
 	w, err := m.SelectWorker(te.Task)
 	if err != nil {
-		log.Printf("Unable to find worker: %v.\n", err)
+		log.Printf("Unable to find worker for task %v: %v.\n", te.Task.ID, err)
+		te.Task.State = task.Pending
+		te.Task.Reason = err.Error()
+		preempted := m.preempt(te.Task)
+		if preempted {
+			te.Task.Reason = "preempting lower priority tasks"
+		}
+		m.TaskDb[te.Task.ID] = &te.Task
+		m.Pending.Enqueue(te)
+		return preempted
 	}
 
-	workerId := w.Name
+	if err := m.assign(&te, w); err != nil {
+		log.Printf("Unable to assign task %v to %s: %v\n", te.Task.ID, w.Name, err)
+		te.Task.Reason = err.Error()
+		m.TaskDb[te.Task.ID] = &te.Task
+		m.Pending.Enqueue(te)
+		return false
+	}
+	m.dispatch(te, w, func(err error) {
+		if err != nil {
+			m.unassign(&te, w)
+			m.Pending.Enqueue(te)
+		}
+	})
+	return false
+}
+
+// assign allocates host ports and reserves w's capacity for the task, recording it as scheduled there.
+func (m *Manager) assign(te *task.TaskEvent, w *node.Node) error {
+	if err := m.Ports.Assign(w, &te.Task); err != nil {
+		return err
+	}
 
-	m.WorkerTaskMap[workerId][te.Task.ID] = true
-	m.TaskWorkerMap[te.Task.ID] = workerId
+	m.WorkerTaskMap[w.Name][te.Task.ID] = true
+	m.TaskWorkerMap[te.Task.ID] = w.Name
 	te.Task.State = task.Scheduled
+	te.Task.Reason = ""
+	m.discoveryConfig(&te.Task)
+	w.Allocate(te.Task)
 
 	m.TaskDb[te.Task.ID] = &te.Task
+	return nil
+}
+
+// unassign undoes assign. Bindings resolved from the task's port requests are dropped too,
+// so a retry on another node picks its host ports afresh.
+func (m *Manager) unassign(te *task.TaskEvent, w *node.Node) {
+	w.Release(te.Task)
+	delete(m.WorkerTaskMap[w.Name], te.Task.ID)
+	delete(m.TaskWorkerMap, te.Task.ID)
+	if len(te.Task.Ports) > 0 {
+		te.Task.PortBindings = nil
+	}
+	te.Task.State = task.Pending
+}
+
+// dispatch queues the task, along with its secrets and configs, for Deliver to send to its worker.
+// done is told whether it arrived: failing to load a secret or config, or to reach the worker,
+// is an error, while a rejection by the worker is only logged.
+func (m *Manager) dispatch(te task.TaskEvent, w *node.Node, done func(err error)) {
+	te, err := m.withSecrets(te)
+	if err != nil {
+		log.Printf("Unable to load secrets for task %v: %v\n", te.Task.ID, err)
+		done(err)
+		return
+	}
+	te, err = m.withConfigs(te)
+	if err != nil {
+		log.Printf("Unable to load configs for task %v: %v\n", te.Task.ID, err)
+		done(err)
+		return
+	}
 
 	data, err := json.Marshal(te)
 	if err != nil {
-		log.Printf("Unable to marshal taskevent object: %v.\n", te)
+		log.Printf("Unable to marshal task event %v: %v\n", te.ID, err)
+		done(err)
+		return
 	}
+	m.outbox = append(m.outbox, delivery{te: te, w: w, data: data, done: done})
+}
 
-	url := fmt.Sprintf("http://%s/tasks", w.Ip)
+// Deliver posts the dispatched task events to their workers without holding the lock,
+// then records every outcome under it.
+func (m *Manager) Deliver() {
+	m.mu.Lock()
+	outbox := m.outbox
+	m.outbox = nil
+	m.mu.Unlock()
+
+	errs := make([]error, len(outbox))
+	for i, d := range outbox {
+		errs[i] = post(d)
+	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	m.mu.Lock()
+	defer m.unlock()
+	for i, d := range outbox {
+		d.done(errs[i])
+	}
+}
+
+func post(d delivery) error {
+	url := fmt.Sprintf("http://%s/tasks", d.w.Ip)
+
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(d.data))
 	if err != nil {
-		log.Printf("Error connecting to %v: %v\n", workerId, err)
-		m.Pending.Enqueue(te)
-		return
+		log.Printf("Error connecting to %v: %v\n", d.w.Name, err)
+		return err
 	}
+	defer resp.Body.Close()
 
 	e := api.StandardResponse[task.Task]{}
 	json.NewDecoder(resp.Body).Decode(&e)
 
 	if resp.StatusCode != http.StatusCreated {
 		log.Printf("Error: %s", e.ErrorMsg)
-		return
+		return nil
 	}
 
-	var taskResult task.Task = e.Response
-	log.Printf("%#v\n", taskResult)
+	log.Printf("Dispatched task %v to %s\n", e.Response.ID, d.w.Name)
+	return nil
 }
 
+// UpdateTasks polls every worker for the state of its tasks. The workers are queried
+// without the lock, which is only taken to record what they report.
 func (m *Manager) UpdateTasks() {
 
 	for _, workerString := range m.Workers {
@@ -123,27 +383,47 @@ func (m *Manager) UpdateTasks() {
 		d := json.NewDecoder(resp.Body)
 		e := api.StandardResponse[[]task.Task]{}
 		err = d.Decode(&e)
+		resp.Body.Close()
 		if err != nil {
 			log.Printf("Error unmarshalling tasks: %s\n", err.Error())
 			return
 		}
 
-		for _, t := range e.Response {
-			log.Printf("Attempting to update task %v\n", t.ID)
-			_, ok := m.TaskDb[t.ID]
-			if !ok {
-				log.Printf("Task with ID %s not found\n", t.ID)
-				return
+		m.updateTasks(e.Response)
+	}
+
+}
+
+func (m *Manager) updateTasks(tasks []task.Task) {
+	m.mu.Lock()
+	defer m.unlock()
+
+	for _, t := range tasks {
+		log.Printf("Attempting to update task %v\n", t.ID)
+		_, ok := m.TaskDb[t.ID]
+		if !ok {
+			log.Printf("Task with ID %s not found\n", t.ID)
+			return
+		}
+		if m.TaskDb[t.ID].State != t.State {
+			m.TaskDb[t.ID].State = t.State
+			if t.Reason != "" {
+				m.TaskDb[t.ID].Reason = t.Reason
 			}
-			if m.TaskDb[t.ID].State != t.State {
-				m.TaskDb[t.ID].State = t.State
+			if t.State == task.Completed || t.State == task.Failed {
+				if n := m.getNode(m.TaskWorkerMap[t.ID]); n != nil {
+					n.Release(*m.TaskDb[t.ID])
+				}
 			}
-			m.TaskDb[t.ID].StartTime = t.StartTime
-			m.TaskDb[t.ID].FinishTime = t.FinishTime
-			m.TaskDb[t.ID].ContainerId = t.ContainerId
 		}
+		m.TaskDb[t.ID].StartTime = t.StartTime
+		m.TaskDb[t.ID].FinishTime = t.FinishTime
+		m.TaskDb[t.ID].ContainerId = t.ContainerId
+		m.TaskDb[t.ID].HostPorts = t.HostPorts
+		m.TaskDb[t.ID].ContainerIP = t.ContainerIP
+		m.TaskDb[t.ID].ExitCode = t.ExitCode
+		m.TaskDb[t.ID].Ready = t.Ready
 	}
-
 }
 
 func (m *Manager) UpdateTasksPeriodically() {
@@ -155,6 +435,54 @@ func (m *Manager) UpdateTasksPeriodically() {
 
 }
 
+func (m *Manager) SendWorkPeriodically() {
+	ticker := time.NewTicker(time.Second * 10)
+	for range ticker.C {
+		m.mu.Lock()
+		m.SendWork()
+		m.ScheduleGangs()
+		m.unlock()
+		m.Deliver()
+	}
+}
+
+// UpdateNodeStats samples every node in parallel into its stats window, which schedulers read instead of polling nodes.
+func (m *Manager) UpdateNodeStats() {
+	var wg sync.WaitGroup
+	for _, n := range m.WorkerNodes {
+		wg.Add(1)
+		go func(n *node.Node) {
+			defer wg.Done()
+			stats, err := n.FetchStats()
+			if err != nil {
+				log.Printf("Error updating stats for node %s: %v\n", n.Name, err)
+				return
+			}
+			m.mu.Lock()
+			n.SetStats(stats, time.Now())
+			m.mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+}
+
+func (m *Manager) UpdateNodeStatsPeriodically() {
+	m.UpdateNodeStats()
+	ticker := time.NewTicker(time.Second * 10)
+	for range ticker.C {
+		m.UpdateNodeStats()
+	}
+}
+
+func (m *Manager) getNode(name string) *node.Node {
+	for _, n := range m.WorkerNodes {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
 func (m *Manager) GetTasks() []*task.Task {
 	tasks := []*task.Task{}
 	for _, v := range m.TaskDb {
@@ -168,57 +496,35 @@ func (m *Manager) DoHealthChecksPeriodically() {
 	for range ticker.C {
 
 		log.Println("Performing task health check")
+		m.mu.Lock()
 		m.doHealthChecks()
+		m.unlock()
 		log.Println("Task health checks completed")
 	}
 }
 
+// doHealthChecks applies restart policies to standalone tasks. Probes run on the workers,
+// which report readiness and fail tasks whose liveness probe fails; owned tasks are left
+// to their service, job or workflow.
 func (m *Manager) doHealthChecks() {
 	for _, v := range m.TaskDb {
-		if v.State == task.Dropped {
+		if v.State == task.Dropped || v.Owner.Kind != "" {
 			continue
 		}
-
-		if v.State == task.Running && v.RestartCount < 3 {
-			err := m.checkTaskHealth(*v)
-			if err != nil {
-				m.restartTask(v)
-			}
-		} else if v.State == task.Failed && v.RestartCount < 3 {
-			m.restartTask(v)
-		}
+		m.applyRestartPolicy(v)
 	}
 }
 
-func (m *Manager) checkTaskHealth(t task.Task) error {
-
-	w := m.TaskWorkerMap[t.ID]
-	hostPort := getHostPort(t.HostPorts)
-	workerHost, _, _ := strings.Cut(w, ":")
-	url := fmt.Sprintf("http://%s:%s%s", workerHost, *hostPort, t.HealthCheck)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		msg := fmt.Sprintf("Error connecting to health check %s", url)
-		log.Println(msg)
-		return errors.New(msg)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		msg := fmt.Sprintf("Error health check for task %s did not return 200\n", t.ID)
-		log.Println(msg)
-		return errors.New(msg)
-	}
-
-	return nil
-}
-
 func (m *Manager) restartTask(t *task.Task) {
-	w := m.TaskWorkerMap[t.ID]
-	t.State = task.Scheduled
 	t.RestartCount++
-	m.TaskDb[t.ID] = t
+	m.redeployTask(t)
+}
 
+// redeployTask starts the task afresh on the worker it is placed on, replacing any container it still has.
+// If its secrets or configs can no longer be loaded, an exited task is marked Failed instead,
+// while a running one is left running as it is, with the error recorded as its reason.
+func (m *Manager) redeployTask(t *task.Task) {
+	w := m.TaskWorkerMap[t.ID]
 	te := task.TaskEvent{
 		ID:        uuid.New(),
 		State:     task.Running,
@@ -226,9 +532,32 @@ func (m *Manager) restartTask(t *task.Task) {
 		Task:      *t,
 	}
 
-	data, err := json.Marshal(te)
+	payload, err := m.withSecrets(te)
+	if err == nil {
+		payload, err = m.withConfigs(payload)
+	}
 	if err != nil {
-		log.Printf("Unable to marshal task object: %v.", t)
+		log.Printf("Unable to redeploy task %v: %v\n", t.ID, err)
+		t.Reason = err.Error()
+		if t.State != task.Running {
+			t.State = task.Failed
+		}
+		return
+	}
+
+	// Exited tasks gave their capacity back, live ones still hold it.
+	exited := t.State == task.Completed || t.State == task.Failed
+	t.State = task.Scheduled
+	t.Ready = false
+	m.TaskDb[t.ID] = t
+	if n := m.getNode(w); n != nil && exited {
+		n.Allocate(*t)
+	}
+	te.Task, payload.Task = *t, *t
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Unable to marshal task event %v: %v\n", te.ID, err)
 		return
 	}
 
@@ -236,7 +565,7 @@ func (m *Manager) restartTask(t *task.Task) {
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
 	if err != nil {
 		log.Printf("Error connecting to %v: %v", w, err)
-		m.Pending.Enqueue(t)
+		m.Pending.Enqueue(te)
 		return
 	}
 
@@ -253,10 +582,10 @@ func (m *Manager) restartTask(t *task.Task) {
 		return
 	}
 
-	log.Printf("%#v\n", e.Response)
+	log.Printf("Redeployed task %v on %s\n", e.Response.ID, w)
 }
 
-func (m *Manager) stopTask(workerIp string, taskID string) {
+func (m *Manager) stopTask(workerIp string, taskID string) error {
 	c := &http.Client{}
 
 	url := fmt.Sprintf("http://%s/tasks/%s", workerIp, taskID)
@@ -266,19 +595,13 @@ func (m *Manager) stopTask(workerIp string, taskID string) {
 
 	if err != nil {
 		log.Printf("Error sending request to worker: %v\n", err)
-		return
+		return err
 	}
+	res.Body.Close()
 
-	if res.StatusCode != http.StatusNoContent {
-		log.Printf("Error sending request: %v\n", err)
-		return
-	}
-
-}
-
-func getHostPort(ports nat.PortMap) *string {
-	for k := range ports {
-		return &ports[k][0].HostPort
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		log.Printf("Error stopping task %s on %s: status %d\n", taskID, workerIp, res.StatusCode)
+		return fmt.Errorf("worker returned %d", res.StatusCode)
 	}
 	return nil
 }
@@ -298,7 +621,6 @@ func New(workers []string, scheduler scheduler.Scheduler) *Manager {
 	}
 
 	return &Manager{
-		Pending:       *queue.New(),
 		TaskDb:        taskDb,
 		EventDb:       eventDb,
 		Workers:       workers,
@@ -306,5 +628,12 @@ func New(workers []string, scheduler scheduler.Scheduler) *Manager {
 		TaskWorkerMap: taskWorkerMap,
 		WorkerNodes:   nodes,
 		Scheduler:     scheduler,
+		Gangs:         make(map[string]*Gang),
+		ServiceDb:     make(map[string]*Service),
+		JobDb:         make(map[string]*Job),
+		CronJobDb:     make(map[string]*CronJob),
+		WorkflowDb:    make(map[string]*Workflow),
+		Secrets:       newEphemeralSecretStore(),
+		ConfigDb:      make(map[string]*ConfigMap),
 	}
 }
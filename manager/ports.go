@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"fmt"
+	"orchard/node"
+	"orchard/task"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultMinHostPort = 20000
+	DefaultMaxHostPort = 32767
+)
+
+// PortAllocator hands out host ports per node, honouring static requests and
+// picking dynamic ones from [Min, Max]. Usage is tracked on each node's PortsAllocated.
+type PortAllocator struct {
+	Min  int
+	Max  int
+	next map[string]int
+}
+
+// Assign resolves t's port requests on n into PortBindings, which the worker passes to the runtime.
+func (pa *PortAllocator) Assign(n *node.Node, t *task.Task) error {
+	if len(t.Ports) == 0 {
+		return nil
+	}
+
+	bindings := make(map[string]string)
+	taken := make(map[string]bool)
+
+	for _, p := range t.Ports {
+		if p.HostPort == 0 {
+			continue
+		}
+		hostPort := strconv.Itoa(p.HostPort)
+		if owner, ok := n.PortsAllocated[hostPort]; (ok && owner != t.ID) || taken[hostPort] {
+			return fmt.Errorf("host port %s in use on node %s", hostPort, n.Name)
+		}
+		bindings[containerPort(p.ContainerPort)] = hostPort
+		taken[hostPort] = true
+	}
+
+	for _, p := range t.Ports {
+		if p.HostPort != 0 {
+			continue
+		}
+		hostPort, err := pa.dynamicPort(n, taken)
+		if err != nil {
+			return err
+		}
+		bindings[containerPort(p.ContainerPort)] = hostPort
+		taken[hostPort] = true
+	}
+
+	t.PortBindings = bindings
+	return nil
+}
+
+func (pa *PortAllocator) dynamicPort(n *node.Node, taken map[string]bool) (string, error) {
+	low, high := pa.Min, pa.Max
+	if low == 0 {
+		low = DefaultMinHostPort
+	}
+	if high == 0 {
+		high = DefaultMaxHostPort
+	}
+	if pa.next == nil {
+		pa.next = make(map[string]int)
+	}
+
+	start := pa.next[n.Name]
+	if start < low || start > high {
+		start = low
+	}
+
+	for i := 0; i <= high-low; i++ {
+		port := low + (start-low+i)%(high-low+1)
+		hostPort := strconv.Itoa(port)
+		if _, used := n.PortsAllocated[hostPort]; used || taken[hostPort] {
+			continue
+		}
+		pa.next[n.Name] = port + 1
+		return hostPort, nil
+	}
+
+	return "", fmt.Errorf("no free host ports in %d-%d on node %s", low, high, n.Name)
+}
+
+func containerPort(port string) string {
+	if strings.Contains(port, "/") {
+		return port
+	}
+	return port + "/tcp"
+}
@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"container/heap"
+	"orchard/task"
+)
+
+type pendingItem struct {
+	event task.TaskEvent
+	seq   int
+}
+
+type pendingHeap []pendingItem
+
+func (h pendingHeap) Len() int { return len(h) }
+
+func (h pendingHeap) Less(i, j int) bool {
+	if h[i].event.Task.Priority != h[j].event.Task.Priority {
+		return h[i].event.Task.Priority > h[j].event.Task.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h pendingHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *pendingHeap) Push(x any) { *h = append(*h, x.(pendingItem)) }
+
+func (h *pendingHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// PendingQueue hands out task events highest priority first, and in arrival order within a priority.
+type PendingQueue struct {
+	items pendingHeap
+	seq   int
+}
+
+func (q *PendingQueue) Enqueue(te task.TaskEvent) {
+	q.seq++
+	heap.Push(&q.items, pendingItem{event: te, seq: q.seq})
+}
+
+func (q *PendingQueue) Dequeue() task.TaskEvent {
+	return heap.Pop(&q.items).(pendingItem).event
+}
+
+func (q *PendingQueue) Len() int {
+	return q.items.Len()
+}
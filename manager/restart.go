@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"orchard/task"
+	"time"
+)
+
+const (
+	RestartBackoffBase = 10 * time.Second
+	RestartBackoffMax  = 5 * time.Minute
+)
+
+// applyRestartPolicy restarts a standalone task that exited when its restart policy asks for
+// it. Each restart waits out an exponential backoff first, during which the task reports
+// CrashLoopBackOff and the time of the next attempt.
+func (m *Manager) applyRestartPolicy(t *task.Task) {
+	exited := t.State == task.Completed || t.State == task.Failed
+	if !exited || stopping(t) {
+		return
+	}
+	// Tasks that never reached a worker, such as members of an aborted gang, have nothing to restart.
+	if _, placed := m.TaskWorkerMap[t.ID]; !placed {
+		return
+	}
+
+	policy := t.Restart()
+	if policy == task.RestartNever || (policy == task.RestartOnFailure && t.State == task.Completed) {
+		return
+	}
+
+	if retries := t.Retries(); retries >= 0 && t.RestartCount >= retries {
+		if reason := fmt.Sprintf("restart limit %d reached", retries); t.Reason != reason {
+			t.NextRestart = time.Time{}
+			t.Reason = reason
+			log.Printf("Task %v: %s, giving up\n", t.ID, reason)
+		}
+		return
+	}
+
+	if t.NextRestart.IsZero() {
+		delay := backoff(RestartBackoffBase, RestartBackoffMax, t.RestartCount+1)
+		t.NextRestart = time.Now().Add(delay)
+		t.Reason = task.CrashLoopBackOff
+		log.Printf("Task %v %s (policy %s), restarting in %v\n", t.ID, t.State, policy, delay)
+		return
+	}
+	if time.Now().Before(t.NextRestart) {
+		return
+	}
+
+	t.NextRestart = time.Time{}
+	t.Reason = ""
+	m.restartTask(t)
+}
+
+// backoff doubles base with every attempt up to limit, adding up to 10% jitter so that
+// tasks failing together do not retry in lockstep.
+func backoff(base time.Duration, limit time.Duration, attempt int) time.Duration {
+	delay := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+	if delay > limit || delay <= 0 {
+		delay = limit
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
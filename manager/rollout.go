@@ -0,0 +1,197 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RolloutProgressing = "Progressing"
+	RolloutComplete    = "Complete"
+	RolloutPaused      = "Paused"
+	RolloutRolledBack  = "RolledBack"
+)
+
+const (
+	DefaultFailureThreshold = 3
+	DefaultProgressDeadline = 10 * time.Minute
+	MaxServiceHistory       = 10
+)
+
+// DeploymentStrategy paces a rolling update: at most Replicas+MaxSurge tasks run at once and at least
+// Replicas-MaxUnavailable stay ready. A revision whose tasks fail FailureThreshold times, or that is not
+// fully ready within ProgressDeadlineSeconds, is rolled back automatically. Paused is left as it
+// was when an update does not set it.
+type DeploymentStrategy struct {
+	MaxSurge                int
+	MaxUnavailable          int
+	Paused                  *bool
+	FailureThreshold        int
+	ProgressDeadlineSeconds int
+}
+
+type ServiceRevision struct {
+	Revision  int
+	Template  task.Task
+	CreatedAt time.Time
+}
+
+func (s *Service) newRevision(template task.Task) {
+	s.Revision++
+	s.Template = template
+	s.History = append(s.History, ServiceRevision{Revision: s.Revision, Template: template, CreatedAt: time.Now()})
+	if len(s.History) > MaxServiceHistory {
+		s.History = s.History[len(s.History)-MaxServiceHistory:]
+	}
+	s.rolloutStarted = time.Now()
+	s.failed = make(map[uuid.UUID]bool)
+	s.rollingBack = false
+	s.Status.Rollout = RolloutProgressing
+}
+
+func splitByRevision(tasks []*task.Task, revision int) ([]*task.Task, []*task.Task) {
+	var current, old []*task.Task
+	for _, t := range tasks {
+		if t.Owner.Revision == revision {
+			current = append(current, t)
+		} else {
+			old = append(old, t)
+		}
+	}
+	return current, old
+}
+
+// rollout replaces old-revision tasks with current ones one surge at a time, only retiring
+// ready old tasks once enough current ones are ready. Old tasks that are not serving do not
+// take up surge room and are retired one per pass.
+func (m *Manager) rollout(s *Service, current []*task.Task, old []*task.Task) {
+	if *s.Strategy.Paused {
+		s.Status.Rollout = RolloutPaused
+		return
+	}
+	s.Status.Rollout = RolloutProgressing
+	if s.rollingBack {
+		s.Status.Rollout = RolloutRolledBack
+	}
+
+	if m.rolloutFailed(s) {
+		return
+	}
+
+	maxSurge, maxUnavailable := s.Strategy.MaxSurge, s.Strategy.MaxUnavailable
+	if maxSurge == 0 && maxUnavailable == 0 {
+		maxSurge = 1
+	}
+
+	var serving, unready []*task.Task
+	for _, t := range old {
+		if t.Ready && t.State == task.Running {
+			serving = append(serving, t)
+		} else {
+			unready = append(unready, t)
+		}
+	}
+
+	total := len(current) + len(serving)
+	for i := 0; len(current)+i < s.Replicas && total+i < s.Replicas+maxSurge; i++ {
+		t := newServiceTask(s)
+		log.Printf("Service %s: starting revision %d replica %v\n", s.Name, s.Revision, t.ID)
+		m.submit(t)
+	}
+
+	minReady := s.Replicas - maxUnavailable
+	spare := s.Status.Ready - minReady
+	retire := serving
+	if len(retire) > spare {
+		retire = retire[:max(spare, 0)]
+	}
+	if len(unready) > 0 {
+		retire = append(retire, unready[0])
+	}
+	for _, t := range retire {
+		log.Printf("Service %s: retiring revision %d replica %v\n", s.Name, t.Owner.Revision, t.ID)
+		m.StopTask(t.ID)
+	}
+}
+
+// rolloutFailed rolls the service back to its previous revision when the current one keeps failing.
+func (m *Manager) rolloutFailed(s *Service) bool {
+	for _, t := range m.TaskDb {
+		if t.Owner.Kind == task.OwnerService && t.Owner.Name == s.Name &&
+			t.Owner.Revision == s.Revision && t.State == task.Failed {
+			s.failed[t.ID] = true
+		}
+	}
+
+	threshold := s.Strategy.FailureThreshold
+	if threshold == 0 {
+		threshold = DefaultFailureThreshold
+	}
+	deadline := time.Duration(s.Strategy.ProgressDeadlineSeconds) * time.Second
+	if deadline == 0 {
+		deadline = DefaultProgressDeadline
+	}
+
+	reason := ""
+	if len(s.failed) >= threshold {
+		reason = fmt.Sprintf("%d tasks of revision %d failed", len(s.failed), s.Revision)
+	} else if time.Since(s.rolloutStarted) > deadline {
+		reason = fmt.Sprintf("revision %d not ready within %v", s.Revision, deadline)
+	}
+	if reason == "" || len(s.History) < 2 || s.rollingBack {
+		return false
+	}
+
+	log.Printf("Service %s: rollout failed: %s\n", s.Name, reason)
+	if _, err := m.RollbackService(s.Name, 0); err != nil {
+		log.Printf("Service %s: unable to roll back: %v\n", s.Name, err)
+		return false
+	}
+	return true
+}
+
+// RollbackService rolls out the template of the given revision again as a new revision.
+// Revision 0 means the one before the current revision.
+func (m *Manager) RollbackService(name string, revision int) (*Service, error) {
+	s, ok := m.ServiceDb[name]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	if revision == 0 {
+		revision = s.Revision - 1
+		if len(s.History) > 1 {
+			revision = s.History[len(s.History)-2].Revision
+		}
+	}
+
+	for _, rev := range s.History {
+		if rev.Revision == revision && rev.Revision != s.Revision {
+			s.newRevision(rev.Template)
+			s.rollingBack = true
+			s.Status.Rollout = RolloutRolledBack
+			log.Printf("Service %s: rolled back to revision %d as revision %d\n", name, revision, s.Revision)
+			m.reconcileService(s)
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("service %s has no revision %d to roll back to", name, revision)
+}
+
+func (m *Manager) PauseService(name string, paused bool) (*Service, error) {
+	s, ok := m.ServiceDb[name]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	s.Strategy.Paused = &paused
+	if !paused {
+		s.rolloutStarted = time.Now()
+	}
+	m.reconcileService(s)
+	return s, nil
+}
@@ -0,0 +1,222 @@
+package manager
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"orchard/task"
+	"os"
+	"time"
+)
+
+// Secret is a named value kept encrypted with AES-GCM. Data holds the nonce followed by the
+// ciphertext and never leaves the manager; API responses use SecretInfo instead.
+type Secret struct {
+	Name      string
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Data      []byte
+}
+
+type SecretInfo struct {
+	Name      string
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (s *Secret) Info() SecretInfo {
+	return SecretInfo{Name: s.Name, Version: s.Version, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+}
+
+// SecretStore holds secrets encrypted under a 32 byte key, and when Path is set keeps
+// the encrypted secrets on disk so they survive a manager restart.
+type SecretStore struct {
+	Path    string
+	aead    cipher.AEAD
+	secrets map[string]*Secret
+}
+
+func NewSecretStore(key []byte, path string) (*SecretStore, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SecretStore{Path: path, aead: aead, secrets: make(map[string]*Secret)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.secrets); err != nil {
+		return nil, fmt.Errorf("reading secrets from %s: %v", path, err)
+	}
+	for name := range s.secrets {
+		if _, err := s.Get(name); err != nil {
+			return nil, fmt.Errorf("secret %s cannot be decrypted with this key: %v", name, err)
+		}
+	}
+	log.Printf("Loaded %d secrets from %s\n", len(s.secrets), path)
+	return s, nil
+}
+
+// newEphemeralSecretStore uses a random key, so its secrets only live as long as the manager.
+func newEphemeralSecretStore() *SecretStore {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	s, _ := NewSecretStore(key, "")
+	return s
+}
+
+func (s *SecretStore) Put(name string, value []byte) (SecretInfo, error) {
+	if name == "" {
+		return SecretInfo{}, fmt.Errorf("secret name is required")
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return SecretInfo{}, err
+	}
+
+	now := time.Now()
+	secret, ok := s.secrets[name]
+	if !ok {
+		secret = &Secret{Name: name, CreatedAt: now}
+	}
+	secret.Version++
+	secret.UpdatedAt = now
+	// The name is bound as additional data so ciphertexts cannot be swapped between secrets.
+	secret.Data = s.aead.Seal(nonce, nonce, value, []byte(name))
+	s.secrets[name] = secret
+
+	if err := s.save(); err != nil {
+		return SecretInfo{}, err
+	}
+	log.Printf("Stored secret %s version %d\n", name, secret.Version)
+	return secret.Info(), nil
+}
+
+func (s *SecretStore) Get(name string) ([]byte, error) {
+	secret, ok := s.secrets[name]
+	if !ok {
+		return nil, fmt.Errorf("secret %s not found", name)
+	}
+
+	size := s.aead.NonceSize()
+	if len(secret.Data) < size {
+		return nil, fmt.Errorf("secret %s is corrupt", name)
+	}
+	return s.aead.Open(nil, secret.Data[:size], secret.Data[size:], []byte(name))
+}
+
+func (s *SecretStore) Delete(name string) error {
+	if _, ok := s.secrets[name]; !ok {
+		return fmt.Errorf("secret %s not found", name)
+	}
+	delete(s.secrets, name)
+	log.Printf("Deleted secret %s\n", name)
+	return s.save()
+}
+
+func (s *SecretStore) List() []SecretInfo {
+	infos := []SecretInfo{}
+	for _, secret := range s.secrets {
+		infos = append(infos, secret.Info())
+	}
+	return infos
+}
+
+func (s *SecretStore) Info(name string) (SecretInfo, bool) {
+	secret, ok := s.secrets[name]
+	if !ok {
+		return SecretInfo{}, false
+	}
+	return secret.Info(), true
+}
+
+func (s *SecretStore) save() error {
+	if s.Path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// DeleteSecret removes a secret unless a live task or a template tasks are still created from references it.
+func (m *Manager) DeleteSecret(name string) error {
+	if user := m.templateUser(func(t task.Task) bool { return referencesSecret(t, name) }); user != "" {
+		return fmt.Errorf("secret %s is in use by %s", name, user)
+	}
+	for _, t := range m.TaskDb {
+		live := t.State == task.Pending || t.State == task.Scheduled || t.State == task.Running
+		if live && referencesSecret(*t, name) {
+			return fmt.Errorf("secret %s is in use by task %v", name, t.ID)
+		}
+	}
+	return m.Secrets.Delete(name)
+}
+
+func referencesSecret(t task.Task, name string) bool {
+	for _, ref := range t.Secrets {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// missingSecret returns why the task cannot be placed because a secret it references does not exist.
+func (m *Manager) missingSecret(t task.Task) string {
+	for _, ref := range t.Secrets {
+		if _, ok := m.Secrets.Info(ref.Name); !ok {
+			return fmt.Sprintf("secret %s not found", ref.Name)
+		}
+	}
+	return ""
+}
+
+// withSecrets attaches the plaintext of the task's secrets to the event sent to its worker.
+// Only this copy carries them; the event kept by the manager never does.
+func (m *Manager) withSecrets(te task.TaskEvent) (task.TaskEvent, error) {
+	if len(te.Task.Secrets) == 0 {
+		return te, nil
+	}
+
+	te.Secrets = make(map[string][]byte, len(te.Task.Secrets))
+	for _, ref := range te.Task.Secrets {
+		value, err := m.Secrets.Get(ref.Name)
+		if err != nil {
+			return te, err
+		}
+		te.Secrets[ref.Name] = value
+	}
+	return te, nil
+}
@@ -0,0 +1,230 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const ServiceLabel = "service"
+
+type ServiceStatus struct {
+	Running int
+	Ready   int
+	Pending int
+	Updated int
+	Rollout string
+}
+
+type Service struct {
+	Name      string
+	Template  task.Task
+	Replicas  int
+	Strategy  DeploymentStrategy
+	Revision  int
+	History   []ServiceRevision
+	CreatedAt time.Time
+	Status    ServiceStatus
+
+	rolloutStarted time.Time
+	failed         map[uuid.UUID]bool
+	rollingBack    bool
+}
+
+func (m *Manager) CreateService(s Service) error {
+	if s.Name == "" {
+		return fmt.Errorf("service name is required")
+	}
+	if _, ok := m.ServiceDb[s.Name]; ok {
+		return fmt.Errorf("service %s already exists", s.Name)
+	}
+
+	if s.Strategy.Paused == nil {
+		s.Strategy.Paused = new(bool)
+	}
+	s.CreatedAt = time.Now()
+	s.History = nil
+	s.Revision = 0
+	s.newRevision(s.Template)
+	m.ServiceDb[s.Name] = &s
+	log.Printf("Created service %s with %d replicas\n", s.Name, s.Replicas)
+	m.reconcileService(&s)
+	return nil
+}
+
+func (m *Manager) UpdateService(name string, s Service) (*Service, error) {
+	existing, ok := m.ServiceDb[name]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	if !reflect.DeepEqual(existing.Template, s.Template) {
+		existing.newRevision(s.Template)
+		log.Printf("Service %s: rolling out revision %d\n", name, existing.Revision)
+	}
+	existing.Replicas = s.Replicas
+	if s.Strategy.Paused == nil {
+		s.Strategy.Paused = existing.Strategy.Paused
+	}
+	existing.Strategy = s.Strategy
+	log.Printf("Updated service %s to %d replicas\n", name, s.Replicas)
+	m.reconcileService(existing)
+	return existing, nil
+}
+
+func (m *Manager) DeleteService(name string) error {
+	s, ok := m.ServiceDb[name]
+	if !ok {
+		return fmt.Errorf("service %s not found", name)
+	}
+
+	for _, t := range m.serviceTasks(s) {
+		m.StopTask(t.ID)
+	}
+	delete(m.ServiceDb, name)
+	log.Printf("Deleted service %s\n", name)
+	return nil
+}
+
+func (m *Manager) GetServices() []*Service {
+	services := []*Service{}
+	for _, s := range m.ServiceDb {
+		services = append(services, s)
+	}
+	return services
+}
+
+func (m *Manager) ReconcileServicesPeriodically() {
+	ticker := time.NewTicker(time.Second * 10)
+	for range ticker.C {
+		m.mu.Lock()
+		m.ReconcileServices()
+		m.unlock()
+	}
+}
+
+func (m *Manager) ReconcileServices() {
+	for _, s := range m.ServiceDb {
+		m.reconcileService(s)
+	}
+}
+
+// reconcileService converges the service's live tasks on its desired replica count:
+// failed or stopped replicas are replaced, and surplus ones are stopped, unplaced ones first.
+// While tasks of an older revision remain, the rollout strategy decides the pace instead.
+func (m *Manager) reconcileService(s *Service) {
+	active := m.serviceTasks(s)
+	current, old := splitByRevision(active, s.Revision)
+
+	s.Status = ServiceStatus{Updated: len(current), Rollout: s.Status.Rollout}
+	for _, t := range active {
+		switch {
+		case t.State != task.Running:
+			s.Status.Pending++
+		case t.Ready:
+			s.Status.Running++
+			s.Status.Ready++
+		default:
+			s.Status.Running++
+		}
+	}
+
+	if len(old) > 0 || *s.Strategy.Paused {
+		m.rollout(s, current, old)
+		return
+	}
+	rolling := s.Status.Rollout == RolloutProgressing || s.Status.Rollout == RolloutRolledBack
+	if rolling && s.Status.Ready >= s.Replicas {
+		log.Printf("Service %s: revision %d rolled out\n", s.Name, s.Revision)
+		s.Status.Rollout = RolloutComplete
+	}
+
+	for i := len(active); i < s.Replicas; i++ {
+		t := newServiceTask(s)
+		log.Printf("Service %s: starting replica %v\n", s.Name, t.ID)
+		m.submit(t)
+	}
+
+	if len(active) > s.Replicas {
+		sort.SliceStable(active, func(i, j int) bool {
+			return active[i].State < active[j].State
+		})
+		for _, t := range active[s.Replicas:] {
+			log.Printf("Service %s: stopping surplus replica %v\n", s.Name, t.ID)
+			m.StopTask(t.ID)
+		}
+	}
+}
+
+// serviceTasks returns the service's tasks that are pending, scheduled or running.
+func (m *Manager) serviceTasks(s *Service) []*task.Task {
+	var tasks []*task.Task
+	for _, t := range m.TaskDb {
+		if t.Owner.Kind != task.OwnerService || t.Owner.Name != s.Name || stopping(t) {
+			continue
+		}
+		if t.State == task.Pending || t.State == task.Scheduled || t.State == task.Running {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// serviceReady reports whether the service has at least one running replica passing its readiness probe.
+func (m *Manager) serviceReady(name string) bool {
+	for _, t := range m.TaskDb {
+		if t.Owner.Kind == task.OwnerService && t.Owner.Name == name &&
+			t.State == task.Running && t.Ready && !stopping(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitingFor returns why the task cannot start yet because of its WaitFor services, if it cannot.
+func (m *Manager) waitingFor(t task.Task) string {
+	for _, name := range t.WaitFor {
+		if _, ok := m.ServiceDb[name]; !ok {
+			return fmt.Sprintf("waiting for unknown service %s", name)
+		}
+		if !m.serviceReady(name) {
+			return fmt.Sprintf("waiting for service %s to be ready", name)
+		}
+	}
+	return ""
+}
+
+// ValidateTask rejects tasks that could never be placed as submitted.
+func (m *Manager) ValidateTask(t task.Task) error {
+	for _, name := range t.WaitFor {
+		if _, ok := m.ServiceDb[name]; !ok {
+			return fmt.Errorf("task waits for unknown service %s", name)
+		}
+	}
+	return ValidateGang(t.Gang)
+}
+
+func newServiceTask(s *Service) task.Task {
+	t := s.Template
+	t.ID = uuid.New()
+	t.Name = fmt.Sprintf("%s-%s", s.Name, t.ID.String()[:8])
+	t.Owner = task.Owner{Kind: task.OwnerService, Name: s.Name, Revision: s.Revision}
+	t.TaskConfig.Name = t.Name
+	t.ContainerId = ""
+	t.HostPorts = nil
+	t.RestartCount = 0
+	t.Reason = ""
+
+	t.Labels = make(map[string]string, len(s.Template.Labels)+1)
+	for k, v := range s.Template.Labels {
+		t.Labels[k] = v
+	}
+	t.Labels[ServiceLabel] = s.Name
+
+	return t
+}
@@ -0,0 +1,365 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WorkflowRunning   = "Running"
+	WorkflowSucceeded = "Succeeded"
+	WorkflowFailed    = "Failed"
+)
+
+const (
+	StepWaiting   = "Waiting"
+	StepRunning   = "Running"
+	StepSucceeded = "Succeeded"
+	StepFailed    = "Failed"
+	StepSkipped   = "Skipped"
+)
+
+// Conditions on a step's upstream outcome.
+const (
+	RunOnSuccess = "Success"
+	RunOnFailure = "Failure"
+	RunAlways    = "Always"
+)
+
+// WorkflowStep runs Template once every step in DependsOn has finished and When holds:
+// Success (the default) needs all of them to succeed, Failure needs at least one to fail,
+// and Always runs regardless. A step whose condition cannot hold is skipped.
+type WorkflowStep struct {
+	Name      string
+	Template  task.Task
+	DependsOn []string
+	When      string
+}
+
+type StepStatus struct {
+	Phase      string
+	TaskID     uuid.UUID
+	StartTime  time.Time
+	FinishTime time.Time
+	Reason     string
+}
+
+type WorkflowStatus struct {
+	Phase          string
+	Steps          map[string]*StepStatus
+	StartTime      time.Time
+	CompletionTime time.Time
+	Duration       time.Duration
+	Reason         string
+}
+
+// Workflow is a DAG of steps. It fails when a step fails without a dependent Failure or Always
+// step to handle it, or when such a handler fails itself.
+type Workflow struct {
+	Name      string
+	Steps     []WorkflowStep
+	CreatedAt time.Time
+	Status    WorkflowStatus
+}
+
+func (m *Manager) CreateWorkflow(wf Workflow) error {
+	if wf.Name == "" {
+		return fmt.Errorf("workflow name is required")
+	}
+	if _, ok := m.WorkflowDb[wf.Name]; ok {
+		return fmt.Errorf("workflow %s already exists", wf.Name)
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+
+	wf.CreatedAt = time.Now()
+	wf.Status = WorkflowStatus{Phase: WorkflowRunning, StartTime: wf.CreatedAt, Steps: make(map[string]*StepStatus)}
+	for _, step := range wf.Steps {
+		wf.Status.Steps[step.Name] = &StepStatus{Phase: StepWaiting}
+	}
+	m.WorkflowDb[wf.Name] = &wf
+	log.Printf("Created workflow %s with %d steps\n", wf.Name, len(wf.Steps))
+	m.reconcileWorkflow(&wf)
+	return nil
+}
+
+// validate checks step names, conditions and dependencies, and rejects cycles.
+func (wf *Workflow) validate() error {
+	if len(wf.Steps) == 0 {
+		return fmt.Errorf("workflow %s has no steps", wf.Name)
+	}
+
+	steps := make(map[string]*WorkflowStep, len(wf.Steps))
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		if step.Name == "" {
+			return fmt.Errorf("workflow %s: step name is required", wf.Name)
+		}
+		if _, ok := steps[step.Name]; ok {
+			return fmt.Errorf("workflow %s: duplicate step %s", wf.Name, step.Name)
+		}
+		switch step.When {
+		case "":
+			step.When = RunOnSuccess
+		case RunOnSuccess, RunOnFailure, RunAlways:
+		default:
+			return fmt.Errorf("workflow %s: step %s has unknown condition %q", wf.Name, step.Name, step.When)
+		}
+		steps[step.Name] = step
+	}
+
+	for _, step := range wf.Steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := steps[dep]; !ok {
+				return fmt.Errorf("workflow %s: step %s depends on unknown step %s", wf.Name, step.Name, dep)
+			}
+		}
+		if step.When != RunOnSuccess && len(step.DependsOn) == 0 {
+			return fmt.Errorf("workflow %s: step %s runs on %s but has no dependencies", wf.Name, step.Name, step.When)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("workflow %s: dependency cycle through step %s", wf.Name, name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range steps[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+	for name := range steps {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) DeleteWorkflow(name string) error {
+	wf, ok := m.WorkflowDb[name]
+	if !ok {
+		return fmt.Errorf("workflow %s not found", name)
+	}
+
+	for _, status := range wf.Status.Steps {
+		if status.Phase == StepRunning {
+			m.StopTask(status.TaskID)
+		}
+	}
+	delete(m.WorkflowDb, name)
+	log.Printf("Deleted workflow %s\n", name)
+	return nil
+}
+
+func (m *Manager) GetWorkflows() []*Workflow {
+	workflows := []*Workflow{}
+	for _, wf := range m.WorkflowDb {
+		workflows = append(workflows, wf)
+	}
+	return workflows
+}
+
+// WorkflowStepView is one step of a workflow along with its current status.
+type WorkflowStepView struct {
+	Name      string
+	DependsOn []string
+	When      string
+	StepStatus
+}
+
+// GetWorkflowSteps returns the workflow's steps in declaration order with their status.
+func (m *Manager) GetWorkflowSteps(name string) ([]WorkflowStepView, error) {
+	wf, ok := m.WorkflowDb[name]
+	if !ok {
+		return nil, fmt.Errorf("workflow %s not found", name)
+	}
+
+	steps := []WorkflowStepView{}
+	for _, step := range wf.Steps {
+		steps = append(steps, WorkflowStepView{
+			Name:       step.Name,
+			DependsOn:  step.DependsOn,
+			When:       step.When,
+			StepStatus: *wf.Status.Steps[step.Name],
+		})
+	}
+	return steps, nil
+}
+
+func (m *Manager) ReconcileWorkflowsPeriodically() {
+	ticker := time.NewTicker(time.Second * 5)
+	for range ticker.C {
+		m.mu.Lock()
+		m.ReconcileWorkflows()
+		m.unlock()
+	}
+}
+
+func (m *Manager) ReconcileWorkflows() {
+	for _, wf := range m.WorkflowDb {
+		m.reconcileWorkflow(wf)
+	}
+}
+
+// reconcileWorkflow records the outcome of running steps, then submits or skips every waiting
+// step whose dependencies have all finished, repeating until nothing more can move.
+func (m *Manager) reconcileWorkflow(wf *Workflow) {
+	if wf.Status.Phase != WorkflowRunning {
+		return
+	}
+
+	for _, step := range wf.Steps {
+		status := wf.Status.Steps[step.Name]
+		if status.Phase == StepRunning {
+			m.updateStep(status)
+		}
+	}
+
+	for progressed := true; progressed; {
+		progressed = false
+		for _, step := range wf.Steps {
+			status := wf.Status.Steps[step.Name]
+			if status.Phase != StepWaiting {
+				continue
+			}
+
+			ready, run := wf.stepCondition(step)
+			if !ready {
+				continue
+			}
+			progressed = true
+
+			if !run {
+				status.Phase = StepSkipped
+				status.Reason = fmt.Sprintf("condition %s not met", step.When)
+				log.Printf("Workflow %s: skipping step %s\n", wf.Name, step.Name)
+				continue
+			}
+
+			t := newWorkflowTask(wf, step)
+			status.Phase = StepRunning
+			status.TaskID = t.ID
+			status.StartTime = time.Now()
+			log.Printf("Workflow %s: starting step %s as task %v\n", wf.Name, step.Name, t.ID)
+			m.submit(t)
+		}
+	}
+
+	m.finishWorkflow(wf)
+}
+
+func (m *Manager) updateStep(status *StepStatus) {
+	t, ok := m.TaskDb[status.TaskID]
+	if !ok {
+		return
+	}
+
+	switch {
+	case t.State == task.Completed && stopping(t):
+		status.Phase = StepFailed
+		status.Reason = t.Reason
+		if status.Reason == "" {
+			status.Reason = "stopped"
+		}
+	case t.State == task.Completed:
+		status.Phase = StepSucceeded
+	case t.State == task.Failed:
+		status.Phase = StepFailed
+		status.Reason = t.Reason
+		if status.Reason == "" {
+			status.Reason = fmt.Sprintf("exit code %d", t.ExitCode)
+		}
+	default:
+		return
+	}
+	status.FinishTime = time.Now()
+}
+
+// stepCondition reports whether all of the step's dependencies have finished and,
+// if so, whether the step should run.
+func (wf *Workflow) stepCondition(step WorkflowStep) (bool, bool) {
+	succeeded, failed := 0, 0
+	for _, dep := range step.DependsOn {
+		switch wf.Status.Steps[dep].Phase {
+		case StepSucceeded:
+			succeeded++
+		case StepFailed:
+			failed++
+		case StepSkipped:
+		default:
+			return false, false
+		}
+	}
+
+	switch step.When {
+	case RunOnFailure:
+		return true, failed > 0
+	case RunAlways:
+		return true, true
+	default:
+		return true, succeeded == len(step.DependsOn)
+	}
+}
+
+// finishWorkflow settles the workflow's phase once every step is done.
+func (m *Manager) finishWorkflow(wf *Workflow) {
+	handled := make(map[string]bool)
+	for _, step := range wf.Steps {
+		status := wf.Status.Steps[step.Name]
+		if status.Phase == StepWaiting || status.Phase == StepRunning {
+			return
+		}
+		if step.When != RunOnSuccess && status.Phase != StepSkipped {
+			for _, dep := range step.DependsOn {
+				handled[dep] = true
+			}
+		}
+	}
+
+	wf.Status.Phase = WorkflowSucceeded
+	for _, step := range wf.Steps {
+		status := wf.Status.Steps[step.Name]
+		if status.Phase == StepFailed && !handled[step.Name] {
+			wf.Status.Phase = WorkflowFailed
+			wf.Status.Reason = fmt.Sprintf("step %s failed: %s", step.Name, status.Reason)
+			break
+		}
+	}
+
+	wf.Status.CompletionTime = time.Now()
+	wf.Status.Duration = wf.Status.CompletionTime.Sub(wf.Status.StartTime)
+	log.Printf("Workflow %s %s after %v %s\n", wf.Name, wf.Status.Phase, wf.Status.Duration, wf.Status.Reason)
+}
+
+func newWorkflowTask(wf *Workflow, step WorkflowStep) task.Task {
+	t := step.Template
+	t.ID = uuid.New()
+	t.Name = fmt.Sprintf("%s-%s-%s", wf.Name, step.Name, t.ID.String()[:8])
+	t.Owner = task.Owner{Kind: task.OwnerWorkflow, Name: wf.Name}
+	t.TaskConfig.Name = t.Name
+	t.ContainerId = ""
+	t.HostPorts = nil
+	t.RestartCount = 0
+	t.Reason = ""
+	return t
+}
@@ -12,6 +12,7 @@ import (
 type CPUMetric struct {
 	TimeStat  cpu.TimesStat
 	RatioUsed float64
+	Cores     int
 }
 
 type Metrics struct {
@@ -35,6 +36,11 @@ func GetCPUMetrics() cpu.TimesStat {
 	return res[0]
 }
 
+func GetCPUCores() int {
+	res, _ := cpu.Counts(true)
+	return res
+}
+
 func getCPUUsage(stats cpu.TimesStat) float64 {
 	idle := stats.Idle + stats.Iowait
 	nonIdle := stats.User + stats.Nice + stats.System + stats.Irq + stats.Softirq + stats.Steal
@@ -63,7 +69,7 @@ func GetFullMetrics() Metrics {
 		Load:   GetLoadMetrics(),
 		Disk:   GetDiskMetrics(),
 		Memory: GetMemoryMetrics(),
-		CPU:    CPUMetric{TimeStat: cpuTimeStates, RatioUsed: getCPUUsage(cpuTimeStates)},
+		CPU:    CPUMetric{TimeStat: cpuTimeStates, RatioUsed: getCPUUsage(cpuTimeStates), Cores: GetCPUCores()},
 	}
 }
 
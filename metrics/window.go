@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+type Sample struct {
+	Time    time.Time
+	Metrics Metrics
+}
+
+// Window keeps the samples collected over the last Size, so utilisation can be derived
+// from consecutive samples instead of sampling twice on demand.
+type Window struct {
+	Size    time.Duration
+	mu      sync.RWMutex
+	samples []Sample
+}
+
+func NewWindow(size time.Duration) *Window {
+	return &Window{Size: size}
+}
+
+func (w *Window) Add(m Metrics, at time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples = append(w.samples, Sample{Time: at, Metrics: m})
+
+	cutoff := at.Add(-w.Size)
+	drop := 0
+	for drop < len(w.samples)-2 && w.samples[drop].Time.Before(cutoff) {
+		drop++
+	}
+	w.samples = w.samples[drop:]
+}
+
+func (w *Window) Latest() (Sample, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if len(w.samples) == 0 {
+		return Sample{}, false
+	}
+	return w.samples[len(w.samples)-1], true
+}
+
+// Age is the time since the latest sample; a window with no samples is infinitely old.
+func (w *Window) Age(now time.Time) time.Duration {
+	latest, ok := w.Latest()
+	if !ok {
+		return time.Duration(1<<63 - 1)
+	}
+	return now.Sub(latest.Time)
+}
+
+// CPUUtilization is the share of non-idle CPU time between the oldest and newest samples.
+func (w *Window) CPUUtilization() float64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if len(w.samples) == 0 {
+		return 0.00
+	}
+
+	newest := w.samples[len(w.samples)-1].Metrics.CPU.TimeStat
+	if len(w.samples) == 1 {
+		return w.samples[0].Metrics.CPU.RatioUsed
+	}
+	oldest := w.samples[0].Metrics.CPU.TimeStat
+
+	idle := (newest.Idle + newest.Iowait) - (oldest.Idle + oldest.Iowait)
+	total := newest.Total() - oldest.Total()
+	if total <= 0 {
+		return 0.00
+	}
+	return (total - idle) / total
+}
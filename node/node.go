@@ -8,20 +8,39 @@ import (
 	"net/http"
 	"orchard/api"
 	"orchard/metrics"
+	"orchard/task"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ZoneLabel = "zone"
+	RackLabel = "rack"
 )
 
+const StatsWindow = time.Minute
+
+var statsClient = http.Client{Timeout: 5 * time.Second}
+
 type Node struct {
 	Name            string
 	Ip              string
 	Api             string
 	Cores           int
+	CpuAllocated    float64
 	Memory          int
 	MemoryAllocated int
 	Disk            int
 	DiskAllocated   int
 	Stats           metrics.Metrics
+	StatsHistory    *metrics.Window `json:"-"`
 	Role            string
+	Labels          map[string]string
+	Taints          []Taint
 	TaskCount       int
+	Tasks           map[uuid.UUID]task.Task
+	PortsAllocated  map[string]uuid.UUID
 }
 
 func NewNode(name string, api string, role string, ip string) *Node {
@@ -30,22 +49,78 @@ func NewNode(name string, api string, role string, ip string) *Node {
 		Ip:   ip,
 		Api:  api,
 		Role: role,
+
+		Labels:         make(map[string]string),
+		StatsHistory:   metrics.NewWindow(StatsWindow),
+		Tasks:          make(map[uuid.UUID]task.Task),
+		PortsAllocated: make(map[string]uuid.UUID),
+	}
+}
+
+func (n *Node) Allocate(t task.Task) {
+	if _, ok := n.Tasks[t.ID]; ok {
+		return
+	}
+	if n.Tasks == nil {
+		n.Tasks = make(map[uuid.UUID]task.Task)
+	}
+	if n.PortsAllocated == nil {
+		n.PortsAllocated = make(map[string]uuid.UUID)
+	}
+
+	n.Tasks[t.ID] = t
+	n.TaskCount++
+	n.CpuAllocated += t.CPU
+	n.MemoryAllocated += t.Memory
+	n.DiskAllocated += t.Disk
+	for _, hostPort := range t.PortBindings {
+		n.PortsAllocated[hostPort] = t.ID
+	}
+}
+
+func (n *Node) Release(t task.Task) {
+	allocated, ok := n.Tasks[t.ID]
+	if !ok {
+		return
+	}
+
+	delete(n.Tasks, t.ID)
+	n.TaskCount--
+	n.CpuAllocated -= allocated.CPU
+	n.MemoryAllocated -= allocated.Memory
+	n.DiskAllocated -= allocated.Disk
+	for _, hostPort := range allocated.PortBindings {
+		if n.PortsAllocated[hostPort] == t.ID {
+			delete(n.PortsAllocated, hostPort)
+		}
 	}
 }
 
 func (n *Node) GetStats() (*metrics.Metrics, error) {
+	stats, err := n.FetchStats()
+	if err != nil {
+		return nil, err
+	}
+	n.SetStats(stats, time.Now())
+	return &n.Stats, nil
+}
+
+// FetchStats retrieves the node's current stats without recording them, so callers can
+// query nodes without holding the lock that guards them.
+func (n *Node) FetchStats() (metrics.Metrics, error) {
 	url := fmt.Sprintf("%s/stats", n.Api)
-	resp, err := http.Get(url)
+	resp, err := statsClient.Get(url)
 	if err != nil {
 		msg := fmt.Sprintf("Unable to connect to %v. Permanent failure.\n", n.Api)
 		log.Println(msg)
-		return nil, errors.New(msg)
+		return metrics.Metrics{}, errors.New(msg)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		msg := fmt.Sprintf("Error retrieving stats from %v: %v", n.Api, err)
 		log.Println(msg)
-		return nil, errors.New(msg)
+		return metrics.Metrics{}, errors.New(msg)
 	}
 
 	var respBody api.StandardResponse[metrics.Metrics]
@@ -55,12 +130,38 @@ func (n *Node) GetStats() (*metrics.Metrics, error) {
 	if err != nil {
 		msg := fmt.Sprintf("error decoding message while getting stats for node %s", n.Name)
 		log.Println(msg)
-		return nil, errors.New(msg)
+		return metrics.Metrics{}, errors.New(msg)
+	}
+	return respBody.Response, nil
+}
+
+// SetStats records stats sampled at the given time as the node's current stats.
+func (n *Node) SetStats(m metrics.Metrics, at time.Time) {
+	n.Memory = int(m.Memory.Total)
+	n.Disk = int(m.Disk.Total)
+	n.Cores = m.CPU.Cores
+	n.Stats = m
+	n.RecordStats(m, at)
+}
+
+func (n *Node) RecordStats(m metrics.Metrics, at time.Time) {
+	if n.StatsHistory == nil {
+		n.StatsHistory = metrics.NewWindow(StatsWindow)
 	}
+	n.StatsHistory.Add(m, at)
+}
 
-	n.Memory = int(respBody.Response.Memory.Total)
-	n.Disk = int(respBody.Response.Disk.Total)
-	n.Stats = respBody.Response
+// CpuUsage is the node's CPU utilisation over the cached stats window.
+func (n *Node) CpuUsage() float64 {
+	if n.StatsHistory == nil {
+		return 0.00
+	}
+	return n.StatsHistory.CPUUtilization()
+}
 
-	return &n.Stats, nil
+func (n *Node) StatsAge() time.Duration {
+	if n.StatsHistory == nil {
+		return time.Duration(1<<63 - 1)
+	}
+	return n.StatsHistory.Age(time.Now())
 }
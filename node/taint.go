@@ -0,0 +1,53 @@
+package node
+
+import (
+	"fmt"
+	"orchard/task"
+)
+
+type TaintEffect string
+
+const (
+	NoSchedule       TaintEffect = "NoSchedule"
+	PreferNoSchedule TaintEffect = "PreferNoSchedule"
+	NoExecute        TaintEffect = "NoExecute"
+)
+
+type Taint struct {
+	Key    string
+	Value  string
+	Effect TaintEffect
+}
+
+func (taint Taint) String() string {
+	return fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect)
+}
+
+func (taint Taint) ToleratedBy(tolerations []task.Toleration) bool {
+	for _, tol := range tolerations {
+		if tol.Effect != "" && tol.Effect != string(taint.Effect) {
+			continue
+		}
+		if tol.Key == "" && tol.Operator == task.TolerateExists {
+			return true
+		}
+		if tol.Key != taint.Key {
+			continue
+		}
+		if tol.Operator == task.TolerateExists || tol.Value == taint.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// UntoleratedTaints returns the node's taints with the given effect that t does not tolerate.
+func (n *Node) UntoleratedTaints(t task.Task, effect TaintEffect) []Taint {
+	var untolerated []Taint
+	for _, taint := range n.Taints {
+		if taint.Effect == effect && !taint.ToleratedBy(t.Tolerations) {
+			untolerated = append(untolerated, taint)
+		}
+	}
+	return untolerated
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"orchard/node"
+	"orchard/task"
+)
+
+type ResourceWeights struct {
+	CPU    float64
+	Memory float64
+	Disk   float64
+}
+
+/*
+Most-allocated placement: prefer the node that would be fullest after placing the task,
+so load is packed tightly and idle workers can be drained.
+*/
+type BinPack struct {
+	Weights ResourceWeights
+}
+
+func (bp *BinPack) SelectCandidateNodes(t task.Task, allNodes []*node.Node) []*node.Node {
+	return filterNodes(t, allNodes)
+}
+
+func (bp *BinPack) ScoreNodes(t task.Task, candidateNodes []*node.Node, allNodes []*node.Node) map[string]float64 {
+	nodeScores := make(map[string]float64)
+
+	for _, n := range candidateNodes {
+		nodeScores[n.Name] = 1 - allocatedRatio(t, n, bp.weights())
+	}
+
+	return applyPreferences(t, candidateNodes, allNodes, nodeScores)
+}
+
+func (bp *BinPack) PickNode(scores map[string]float64, candidateNodes []*node.Node) *node.Node {
+	return lowestScoring(scores, candidateNodes)
+}
+
+func (bp *BinPack) Name() string {
+	return "bin-pack"
+}
+
+func (bp *BinPack) weights() ResourceWeights {
+	if bp.Weights == (ResourceWeights{}) {
+		return ResourceWeights{CPU: 1, Memory: 1, Disk: 1}
+	}
+	return bp.Weights
+}
+
+// allocatedRatio is the weighted share of the node's capacity in use once t is placed on it.
+// Resources the node has not reported a capacity for are left out.
+func allocatedRatio(t task.Task, n *node.Node, w ResourceWeights) float64 {
+	var used, total float64
+
+	if n.Cores > 0 {
+		used += w.CPU * (n.CpuAllocated + t.CPU) / float64(n.Cores)
+		total += w.CPU
+	}
+	if n.Memory > 0 {
+		used += w.Memory * float64(n.MemoryAllocated+t.Memory) / float64(n.Memory)
+		total += w.Memory
+	}
+	if n.Disk > 0 {
+		used += w.Disk * float64(n.DiskAllocated+t.Disk) / float64(n.Disk)
+		total += w.Disk
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return used / total
+}
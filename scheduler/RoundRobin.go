@@ -10,10 +10,10 @@ type RoundRobin struct {
 }
 
 func (rr *RoundRobin) SelectCandidateNodes(t task.Task, allNodes []*node.Node) []*node.Node {
-	return allNodes
+	return filterNodes(t, allNodes)
 }
 
-func (rr *RoundRobin) ScoreNodes(t task.Task, candidateNodes []*node.Node) map[string]float64 {
+func (rr *RoundRobin) ScoreNodes(t task.Task, candidateNodes []*node.Node, allNodes []*node.Node) map[string]float64 {
 
 	nodeScores := make(map[string]float64)
 	var newWorker int = rr.LastWorker
@@ -29,28 +29,20 @@ func (rr *RoundRobin) ScoreNodes(t task.Task, candidateNodes []*node.Node) map[s
 
 	}
 
-	return nodeScores
+	return applyPreferences(t, candidateNodes, allNodes, nodeScores)
 }
 
 func (rr *RoundRobin) PickNode(scores map[string]float64, candidateNodes []*node.Node) *node.Node {
-	var bestNode *node.Node
-	var lowestScore float64
-	for idx, v := range candidateNodes {
-		if idx == 0 {
-			bestNode = v
-			lowestScore = scores[v.Name]
-			continue
-		}
-
-		if scores[v.Name] < lowestScore {
-			bestNode = v
-			lowestScore = scores[v.Name]
-		}
-	}
-
-	return bestNode
+	return lowestScoring(scores, candidateNodes)
 }
 
 func (rr *RoundRobin) Name() string {
 	return "round-robin"
 }
+
+func (rr *RoundRobin) Snapshot() func() {
+	lastWorker := rr.LastWorker
+	return func() {
+		rr.LastWorker = lastWorker
+	}
+}
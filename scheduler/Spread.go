@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"orchard/node"
+	"orchard/task"
+)
+
+const DefaultGroupLabel = "group"
+
+/*
+Spreads tasks sharing the same GroupLabel value across the failure domains named by
+TopologyKey (e.g. zone or rack), scoring each node by how many group members already
+run in its domain.
+*/
+type Spread struct {
+	TopologyKey string
+	GroupLabel  string
+}
+
+func (sp *Spread) SelectCandidateNodes(t task.Task, allNodes []*node.Node) []*node.Node {
+	return filterNodes(t, allNodes)
+}
+
+func (sp *Spread) ScoreNodes(t task.Task, candidateNodes []*node.Node, allNodes []*node.Node) map[string]float64 {
+	nodeScores := make(map[string]float64)
+
+	for _, n := range candidateNodes {
+		nodeScores[n.Name] = spreadScore(t, n, allNodes, sp.topologyKey(), sp.groupLabel())
+	}
+
+	return applyPreferences(t, candidateNodes, allNodes, nodeScores)
+}
+
+func (sp *Spread) PickNode(scores map[string]float64, candidateNodes []*node.Node) *node.Node {
+	return lowestScoring(scores, candidateNodes)
+}
+
+func (sp *Spread) Name() string {
+	return "spread"
+}
+
+func (sp *Spread) topologyKey() string {
+	if sp.TopologyKey == "" {
+		return node.ZoneLabel
+	}
+	return sp.TopologyKey
+}
+
+func (sp *Spread) groupLabel() string {
+	if sp.GroupLabel == "" {
+		return DefaultGroupLabel
+	}
+	return sp.GroupLabel
+}
+
+// topologyDomain is the node's value for the key; unlabelled nodes form a domain of their own.
+func topologyDomain(n *node.Node, topologyKey string) string {
+	if value, ok := n.Labels[topologyKey]; ok {
+		return value
+	}
+	return "node:" + n.Name
+}
+
+// spreadScore counts the tasks of t's group in n's domain. The node's own task count is added as a
+// fraction so that, within a domain, the emptier node wins the tie.
+func spreadScore(t task.Task, n *node.Node, allNodes []*node.Node, topologyKey string, groupLabel string) float64 {
+	group, ok := t.Labels[groupLabel]
+	if !ok {
+		return float64(n.TaskCount)
+	}
+
+	domain := topologyDomain(n, topologyKey)
+	members := 0
+	for _, other := range allNodes {
+		if topologyDomain(other, topologyKey) != domain {
+			continue
+		}
+		for _, running := range other.Tasks {
+			if running.Labels[groupLabel] == group {
+				members++
+			}
+		}
+	}
+
+	return float64(members) + float64(n.TaskCount)/float64(n.TaskCount+1)
+}
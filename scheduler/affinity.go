@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"orchard/node"
+	"orchard/task"
+)
+
+func checkNodeSelector(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	for k, v := range t.NodeSelector {
+		if n.Labels[k] != v {
+			return false, "node selector mismatch"
+		}
+	}
+	return true, ""
+}
+
+func checkNodeAffinity(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	required := t.Affinity.Node.Required
+	if len(required) == 0 {
+		return true, ""
+	}
+
+	for _, term := range required {
+		if term.Matches(n.Labels) {
+			return true, ""
+		}
+	}
+	return false, "node affinity mismatch"
+}
+
+func checkTaskAffinity(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	for _, term := range t.Affinity.TaskAffinity {
+		if term.Required && !domainRuns(term, t, n, allNodes) {
+			return false, "task affinity mismatch"
+		}
+	}
+	return true, ""
+}
+
+func checkTaskAntiAffinity(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	for _, term := range t.Affinity.TaskAnti {
+		if term.Required && domainRuns(term, t, n, allNodes) {
+			return false, "task anti-affinity conflict"
+		}
+	}
+	return true, ""
+}
+
+// domainRuns reports whether any task selected by the term, other than t itself,
+// runs in n's topology domain.
+func domainRuns(term task.TaskAffinityTerm, t task.Task, n *node.Node, allNodes []*node.Node) bool {
+	domain := topologyDomain(n, term.TopologyKey)
+
+	for _, other := range allNodes {
+		if topologyDomain(other, term.TopologyKey) != domain {
+			continue
+		}
+		for _, running := range other.Tasks {
+			if running.ID != t.ID && term.Selects(running) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// preferenceScore turns the task's preferred node affinity, task affinity and task anti-affinity
+// into a score in [-1, 1], lower being better, to be added to a scheduler's own score.
+func preferenceScore(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	var matched, total float64
+
+	for _, pref := range t.Affinity.Node.Preferred {
+		total += float64(pref.Weight)
+		if pref.Term.Matches(n.Labels) {
+			matched += float64(pref.Weight)
+		}
+	}
+
+	for _, term := range t.Affinity.TaskAffinity {
+		if term.Required {
+			continue
+		}
+		total += float64(term.Weight)
+		if domainRuns(term, t, n, allNodes) {
+			matched += float64(term.Weight)
+		}
+	}
+
+	for _, term := range t.Affinity.TaskAnti {
+		if term.Required {
+			continue
+		}
+		total += float64(term.Weight)
+		if domainRuns(term, t, n, allNodes) {
+			matched -= float64(term.Weight)
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return -matched / total
+}
+
+func applyPreferences(t task.Task, candidateNodes []*node.Node, allNodes []*node.Node, scores map[string]float64) map[string]float64 {
+	for _, n := range candidateNodes {
+		scores[n.Name] += preferenceScore(t, n, allNodes) + taintPenalty(t, n)
+	}
+	return scores
+}
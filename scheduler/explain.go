@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"orchard/node"
+	"orchard/task"
+)
+
+// Stateful schedulers change their own state while scheduling. Snapshot returns a func that restores it,
+// so a dry run leaves the next real placement unaffected.
+type Stateful interface {
+	Snapshot() func()
+}
+
+type NodeExplanation struct {
+	Node   string
+	Passed bool
+	Reason string
+	Score  *float64
+}
+
+type Explanation struct {
+	Scheduler string
+	Nodes     []NodeExplanation
+	Selected  string
+	Reason    string
+}
+
+// Explain runs s against allNodes without placing t, recording the filter verdict and score for every node.
+func Explain(s Scheduler, t task.Task, allNodes []*node.Node) Explanation {
+	if stateful, ok := s.(Stateful); ok {
+		defer stateful.Snapshot()()
+	}
+
+	explanation := Explanation{Scheduler: s.Name()}
+
+	candidateNodes := s.SelectCandidateNodes(t, allNodes)
+	candidates := make(map[string]bool)
+	for _, n := range candidateNodes {
+		candidates[n.Name] = true
+	}
+
+	var scores map[string]float64
+	if len(candidateNodes) > 0 {
+		scores = s.ScoreNodes(t, candidateNodes, allNodes)
+		if picked := s.PickNode(scores, candidateNodes); picked != nil {
+			explanation.Selected = picked.Name
+		}
+	} else {
+		explanation.Reason = UnschedulableReason(s, t, allNodes)
+	}
+
+	for _, n := range allNodes {
+		ne := NodeExplanation{Node: n.Name, Passed: candidates[n.Name]}
+		if ne.Passed {
+			score := scores[n.Name]
+			ne.Score = &score
+		} else {
+			ne.Reason = RejectReason(s, t, n, allNodes)
+		}
+		explanation.Nodes = append(explanation.Nodes, ne)
+	}
+
+	if binder, ok := s.(Binder); ok && explanation.Selected != "" {
+		for _, n := range candidateNodes {
+			if n.Name == explanation.Selected {
+				if err := binder.Bind(t, n); err != nil {
+					explanation.Reason = err.Error()
+				}
+			}
+		}
+	}
+
+	return explanation
+}
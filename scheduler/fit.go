@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"fmt"
+	"orchard/node"
+	"orchard/task"
+	"sort"
+	"strings"
+)
+
+// A Predicate reports whether a task fits on a node, and if not, why.
+// allNodes is the full set of nodes being considered, for predicates that look beyond a single node.
+type Predicate func(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string)
+
+var FitPredicates = []Predicate{
+	checkCpu,
+	checkMemory,
+	checkDisk,
+	checkPorts,
+}
+
+var PlacementPredicates = []Predicate{
+	checkTaints,
+	checkNodeSelector,
+	checkNodeAffinity,
+	checkTaskAffinity,
+	checkTaskAntiAffinity,
+}
+
+func checkCpu(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return t.CPU <= float64(n.Cores)-n.CpuAllocated, "insufficient cpu"
+}
+
+func checkMemory(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return t.Memory <= n.Memory-n.MemoryAllocated, "insufficient memory"
+}
+
+func checkDisk(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return t.Disk <= n.Disk-n.DiskAllocated, "insufficient disk"
+}
+
+func checkPorts(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	requested := t.StaticHostPorts()
+	for _, hostPort := range t.PortBindings {
+		requested = append(requested, hostPort)
+	}
+
+	for _, hostPort := range requested {
+		if owner, ok := n.PortsAllocated[hostPort]; ok && owner != t.ID {
+			return false, fmt.Sprintf("host port %s in use", hostPort)
+		}
+	}
+	return true, ""
+}
+
+// CheckFit runs every fit predicate and returns the reason of the first one that rejects the node.
+func CheckFit(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return runPredicates(FitPredicates, t, n, allNodes)
+}
+
+// CheckPlacement runs the node selector and affinity predicates.
+func CheckPlacement(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return runPredicates(PlacementPredicates, t, n, allNodes)
+}
+
+// CheckNode runs every predicate a scheduler applies in SelectCandidateNodes.
+func CheckNode(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	if ok, reason := CheckFit(t, n, allNodes); !ok {
+		return false, reason
+	}
+	return CheckPlacement(t, n, allNodes)
+}
+
+func runPredicates(predicates []Predicate, t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	for _, predicate := range predicates {
+		if ok, reason := predicate(t, n, allNodes); !ok {
+			return false, reason
+		}
+	}
+	return true, ""
+}
+
+func filterNodes(t task.Task, allNodes []*node.Node) []*node.Node {
+	var candidateNodes []*node.Node
+
+	for _, applicantNode := range allNodes {
+		if ok, _ := CheckNode(t, applicantNode, allNodes); ok {
+			candidateNodes = append(candidateNodes, applicantNode)
+		}
+	}
+
+	return candidateNodes
+}
+
+// UnschedulableReason summarises why no node can take the task, e.g.
+// "unschedulable: insufficient memory on 3/3 nodes".
+func UnschedulableReason(s Scheduler, t task.Task, allNodes []*node.Node) string {
+	if len(allNodes) == 0 {
+		return "unschedulable: no nodes available"
+	}
+
+	counts := make(map[string]int)
+	for _, n := range allNodes {
+		if reason := RejectReason(s, t, n, allNodes); reason != "" {
+			counts[reason]++
+		}
+	}
+
+	if len(counts) == 0 {
+		return "unschedulable: no node selected by scheduler"
+	}
+
+	reasons := make([]string, 0, len(counts))
+	for reason, count := range counts {
+		reasons = append(reasons, fmt.Sprintf("%s on %d/%d nodes", reason, count, len(allNodes)))
+	}
+	sort.Strings(reasons)
+
+	return "unschedulable: " + strings.Join(reasons, ", ")
+}
@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"orchard/node"
+	"orchard/task"
+	"os"
+)
+
+type FilterPlugin interface {
+	Name() string
+	Filter(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string)
+}
+
+// ScorePlugin scores a candidate node, lower is better. allNodes is every node seen in the current scheduling cycle.
+type ScorePlugin interface {
+	Name() string
+	Score(t task.Task, n *node.Node, allNodes []*node.Node) float64
+}
+
+type BindPlugin interface {
+	Name() string
+	Bind(t task.Task, n *node.Node) error
+}
+
+// Binder is implemented by schedulers that need a final say once a node has been picked.
+type Binder interface {
+	Bind(t task.Task, n *node.Node) error
+}
+
+type WeightedScorePlugin struct {
+	Plugin ScorePlugin
+	Weight float64
+}
+
+// FrameworkConfig is loaded from JSON, e.g.
+//
+//	{"Filters": ["ResourceFit"], "Scores": [{"Name": "Epvm", "Weight": 2}, {"Name": "TaskSpread", "Weight": 1}], "Binds": ["ResourceFit"]}
+//
+// A score plugin's Args are decoded into the plugin itself, e.g. the resource weights of MostAllocated:
+//
+//	{"Name": "MostAllocated", "Args": {"Weights": {"CPU": 2, "Memory": 1, "Disk": 0.5}}}
+type FrameworkConfig struct {
+	Filters []string
+	Scores  []ScorePluginConfig
+	Binds   []string
+}
+
+type ScorePluginConfig struct {
+	Name   string
+	Weight float64
+	Args   json.RawMessage
+}
+
+var filterRegistry = map[string]func() FilterPlugin{
+	"ResourceFit":     func() FilterPlugin { return &ResourceFit{} },
+	"NodeAffinity":    func() FilterPlugin { return &NodeAffinity{} },
+	"TaskAffinity":    func() FilterPlugin { return &TaskAffinity{} },
+	"TaintToleration": func() FilterPlugin { return &TaintToleration{} },
+}
+
+var scoreRegistry = map[string]func() ScorePlugin{
+	"Epvm":            func() ScorePlugin { return &EpvmCost{} },
+	"TaskSpread":      func() ScorePlugin { return &TaskSpread{} },
+	"MostAllocated":   func() ScorePlugin { return &MostAllocated{} },
+	"TopologySpread":  func() ScorePlugin { return &TopologySpread{} },
+	"Affinity":        func() ScorePlugin { return &PreferredAffinity{} },
+	"TaintToleration": func() ScorePlugin { return &TaintToleration{} },
+}
+
+var bindRegistry = map[string]func() BindPlugin{
+	"ResourceFit": func() BindPlugin { return &ResourceFit{} },
+}
+
+func RegisterFilterPlugin(name string, factory func() FilterPlugin) {
+	filterRegistry[name] = factory
+}
+
+func RegisterScorePlugin(name string, factory func() ScorePlugin) {
+	scoreRegistry[name] = factory
+}
+
+func RegisterBindPlugin(name string, factory func() BindPlugin) {
+	bindRegistry[name] = factory
+}
+
+type Framework struct {
+	Filters []FilterPlugin
+	Scores  []WeightedScorePlugin
+	Binds   []BindPlugin
+}
+
+func NewFramework(config FrameworkConfig) (*Framework, error) {
+	f := &Framework{}
+
+	for _, name := range config.Filters {
+		factory, ok := filterRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter plugin %s", name)
+		}
+		f.Filters = append(f.Filters, factory())
+	}
+
+	for _, sc := range config.Scores {
+		factory, ok := scoreRegistry[sc.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown score plugin %s", sc.Name)
+		}
+		plugin := factory()
+		if len(sc.Args) > 0 {
+			if err := json.Unmarshal(sc.Args, plugin); err != nil {
+				return nil, fmt.Errorf("invalid args for score plugin %s: %v", sc.Name, err)
+			}
+		}
+		weight := sc.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		f.Scores = append(f.Scores, WeightedScorePlugin{Plugin: plugin, Weight: weight})
+	}
+
+	for _, name := range config.Binds {
+		factory, ok := bindRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown bind plugin %s", name)
+		}
+		f.Binds = append(f.Binds, factory())
+	}
+
+	return f, nil
+}
+
+func LoadFrameworkConfig(path string) (FrameworkConfig, error) {
+	var config FrameworkConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading scheduler config %s: %v", path, err)
+	}
+
+	if err = json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("error decoding scheduler config %s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+func NewFrameworkFromFile(path string) (*Framework, error) {
+	config, err := LoadFrameworkConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFramework(config)
+}
+
+func (f *Framework) SelectCandidateNodes(t task.Task, allNodes []*node.Node) []*node.Node {
+	var candidateNodes []*node.Node
+
+	for _, applicantNode := range allNodes {
+		if ok, _ := f.CheckNode(t, applicantNode, allNodes); ok {
+			candidateNodes = append(candidateNodes, applicantNode)
+		}
+	}
+
+	return candidateNodes
+}
+
+func (f *Framework) CheckNode(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	for _, plugin := range f.Filters {
+		if ok, reason := plugin.Filter(t, n, allNodes); !ok {
+			return false, reason
+		}
+	}
+	return true, ""
+}
+
+// ScoreNodes min-max normalises every plugin's scores across the candidates before weighting,
+// so plugins on different scales can be combined.
+func (f *Framework) ScoreNodes(t task.Task, candidateNodes []*node.Node, allNodes []*node.Node) map[string]float64 {
+	nodeScores := make(map[string]float64)
+	for _, n := range candidateNodes {
+		nodeScores[n.Name] = 0
+	}
+
+	for _, wp := range f.Scores {
+		raw := make(map[string]float64)
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, n := range candidateNodes {
+			score := wp.Plugin.Score(t, n, allNodes)
+			raw[n.Name] = score
+			low = math.Min(low, score)
+			high = math.Max(high, score)
+		}
+
+		for name, score := range raw {
+			if high > low {
+				nodeScores[name] += wp.Weight * (score - low) / (high - low)
+			}
+		}
+	}
+
+	return nodeScores
+}
+
+func (f *Framework) PickNode(scores map[string]float64, candidateNodes []*node.Node) *node.Node {
+	return lowestScoring(scores, candidateNodes)
+}
+
+func (f *Framework) Bind(t task.Task, n *node.Node) error {
+	for _, plugin := range f.Binds {
+		if err := plugin.Bind(t, n); err != nil {
+			return fmt.Errorf("bind plugin %s rejected node %s: %v", plugin.Name(), n.Name, err)
+		}
+	}
+	return nil
+}
+
+func (f *Framework) Name() string {
+	return "framework"
+}
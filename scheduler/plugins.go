@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"errors"
+	"orchard/node"
+	"orchard/task"
+)
+
+type ResourceFit struct {
+}
+
+func (rf *ResourceFit) Name() string {
+	return "ResourceFit"
+}
+
+func (rf *ResourceFit) Filter(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return CheckFit(t, n, allNodes)
+}
+
+func (rf *ResourceFit) Bind(t task.Task, n *node.Node) error {
+	if ok, reason := CheckFit(t, n, nil); !ok {
+		return errors.New(reason)
+	}
+	return nil
+}
+
+type EpvmCost struct {
+}
+
+func (ec *EpvmCost) Name() string {
+	return "Epvm"
+}
+
+func (ec *EpvmCost) Score(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	return epvmCost(t, n)
+}
+
+type TaskSpread struct {
+}
+
+func (ts *TaskSpread) Name() string {
+	return "TaskSpread"
+}
+
+func (ts *TaskSpread) Score(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	return float64(n.TaskCount)
+}
+
+type MostAllocated struct {
+	Weights ResourceWeights
+}
+
+func (ma *MostAllocated) Name() string {
+	return "MostAllocated"
+}
+
+func (ma *MostAllocated) Score(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	bp := BinPack{Weights: ma.Weights}
+	return 1 - allocatedRatio(t, n, bp.weights())
+}
+
+type TopologySpread struct {
+	TopologyKey string
+	GroupLabel  string
+}
+
+func (ts *TopologySpread) Name() string {
+	return "TopologySpread"
+}
+
+func (ts *TopologySpread) Score(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	sp := Spread{TopologyKey: ts.TopologyKey, GroupLabel: ts.GroupLabel}
+	return spreadScore(t, n, allNodes, sp.topologyKey(), sp.groupLabel())
+}
+
+type NodeAffinity struct {
+}
+
+func (na *NodeAffinity) Name() string {
+	return "NodeAffinity"
+}
+
+func (na *NodeAffinity) Filter(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return runPredicates([]Predicate{checkNodeSelector, checkNodeAffinity}, t, n, allNodes)
+}
+
+type TaskAffinity struct {
+}
+
+func (ta *TaskAffinity) Name() string {
+	return "TaskAffinity"
+}
+
+func (ta *TaskAffinity) Filter(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return runPredicates([]Predicate{checkTaskAffinity, checkTaskAntiAffinity}, t, n, allNodes)
+}
+
+type PreferredAffinity struct {
+}
+
+func (pa *PreferredAffinity) Name() string {
+	return "Affinity"
+}
+
+func (pa *PreferredAffinity) Score(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	return preferenceScore(t, n, allNodes)
+}
+
+type TaintToleration struct {
+}
+
+func (tt *TaintToleration) Name() string {
+	return "TaintToleration"
+}
+
+func (tt *TaintToleration) Filter(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	return checkTaints(t, n, allNodes)
+}
+
+func (tt *TaintToleration) Score(t task.Task, n *node.Node, allNodes []*node.Node) float64 {
+	return taintPenalty(t, n)
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"orchard/node"
+	"orchard/task"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type Preemption struct {
+	Node    *node.Node
+	Victims []task.Task
+}
+
+// SelectVictims finds the node where evicting the fewest lower-priority tasks lets s place t,
+// preferring victims of the lowest priority. It returns nil when no such node exists.
+func SelectVictims(s Scheduler, t task.Task, allNodes []*node.Node) *Preemption {
+	var best *Preemption
+
+	for _, n := range allNodes {
+		victims, ok := victimsOn(s, t, n, allNodes)
+		if !ok {
+			continue
+		}
+		if best == nil || len(victims) < len(best.Victims) ||
+			(len(victims) == len(best.Victims) && highestPriority(victims) < highestPriority(best.Victims)) {
+			best = &Preemption{Node: n, Victims: victims}
+		}
+	}
+
+	return best
+}
+
+// victimsOn releases lower-priority tasks, lowest first, on a copy of n until t fits.
+func victimsOn(s Scheduler, t task.Task, n *node.Node, allNodes []*node.Node) ([]task.Task, bool) {
+	var lower []task.Task
+	for _, running := range n.Tasks {
+		if running.Priority < t.Priority {
+			lower = append(lower, running)
+		}
+	}
+	if len(lower) == 0 {
+		return nil, false
+	}
+	sort.Slice(lower, func(i, j int) bool { return lower[i].Priority < lower[j].Priority })
+
+	trial := *n
+	trial.Tasks = make(map[uuid.UUID]task.Task, len(n.Tasks))
+	for id, running := range n.Tasks {
+		trial.Tasks[id] = running
+	}
+	trial.PortsAllocated = make(map[string]uuid.UUID, len(n.PortsAllocated))
+	for port, id := range n.PortsAllocated {
+		trial.PortsAllocated[port] = id
+	}
+
+	trialNodes := make([]*node.Node, len(allNodes))
+	for i, other := range allNodes {
+		trialNodes[i] = other
+		if other == n {
+			trialNodes[i] = &trial
+		}
+	}
+
+	var victims []task.Task
+	for _, victim := range lower {
+		trial.Release(victim)
+		victims = append(victims, victim)
+		if RejectReason(s, t, &trial, trialNodes) == "" {
+			return victims, true
+		}
+	}
+	return nil, false
+}
+
+func highestPriority(tasks []task.Task) int {
+	highest := tasks[0].Priority
+	for _, t := range tasks[1:] {
+		if t.Priority > highest {
+			highest = t.Priority
+		}
+	}
+	return highest
+}
@@ -7,7 +7,44 @@ import (
 
 type Scheduler interface {
 	SelectCandidateNodes(t task.Task, allNodes []*node.Node) []*node.Node
-	ScoreNodes(t task.Task, candidateNodes []*node.Node) map[string]float64
+	ScoreNodes(t task.Task, candidateNodes []*node.Node, allNodes []*node.Node) map[string]float64
 	PickNode(scores map[string]float64, candidateNodes []*node.Node) *node.Node
 	Name() string
 }
+
+// NodeChecker is implemented by schedulers that filter on more than the shared predicates,
+// so the reason a node was rejected can be reported.
+type NodeChecker interface {
+	CheckNode(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string)
+}
+
+// RejectReason reports why s would not consider n for t, or "" if it would.
+func RejectReason(s Scheduler, t task.Task, n *node.Node, allNodes []*node.Node) string {
+	var ok bool
+	var reason string
+
+	if checker, isChecker := s.(NodeChecker); isChecker {
+		ok, reason = checker.CheckNode(t, n, allNodes)
+	} else {
+		ok, reason = CheckNode(t, n, allNodes)
+	}
+
+	if ok {
+		return ""
+	}
+	return reason
+}
+
+// lowestScoring picks the candidate with the lowest score, the earliest one on ties.
+func lowestScoring(scores map[string]float64, candidateNodes []*node.Node) *node.Node {
+	var bestNode *node.Node
+	var lowestScore float64
+	for idx, v := range candidateNodes {
+		if idx == 0 || scores[v.Name] < lowestScore {
+			bestNode = v
+			lowestScore = scores[v.Name]
+		}
+	}
+
+	return bestNode
+}
@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"fmt"
+	"orchard/node"
+	"orchard/task"
+)
+
+func checkTaints(t task.Task, n *node.Node, allNodes []*node.Node) (bool, string) {
+	for _, effect := range []node.TaintEffect{node.NoSchedule, node.NoExecute} {
+		if untolerated := n.UntoleratedTaints(t, effect); len(untolerated) > 0 {
+			return false, fmt.Sprintf("untolerated taint %s", untolerated[0])
+		}
+	}
+	return true, ""
+}
+
+// taintPenalty pushes tasks away from nodes with PreferNoSchedule taints they do not tolerate.
+func taintPenalty(t task.Task, n *node.Node) float64 {
+	if len(n.UntoleratedTaints(t, node.PreferNoSchedule)) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,307 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"orchard/metrics"
+	"orchard/node"
+	"orchard/scheduler"
+	"orchard/task"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NodeSpec struct {
+	Name   string
+	Count  int
+	Cores  int
+	Memory int
+	Disk   int
+	Labels map[string]string
+}
+
+type Cluster struct {
+	Nodes []NodeSpec
+}
+
+// TraceEntry is one task arrival; Arrival and Duration are in seconds of virtual time.
+type TraceEntry struct {
+	Name     string
+	Arrival  float64
+	Duration float64
+	CPU      float64
+	Memory   int
+	Disk     int
+	Labels   map[string]string
+}
+
+type Report struct {
+	Scheduler         string
+	Tasks             int
+	Placed            int
+	Unschedulable     int
+	FailedAttempts    int
+	MeanQueueWait     time.Duration
+	MaxQueueWait      time.Duration
+	CPUUtilization    float64
+	MemoryUtilization float64
+	Fragmentation     float64
+	Makespan          time.Duration
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%s: %d/%d placed, %d unschedulable (%d failed attempts), queue wait mean %v max %v, cpu %.1f%%, memory %.1f%%, fragmentation %.1f%%, makespan %v",
+		r.Scheduler, r.Placed, r.Tasks, r.Unschedulable, r.FailedAttempts, r.MeanQueueWait, r.MaxQueueWait,
+		r.CPUUtilization*100, r.MemoryUtilization*100, r.Fragmentation*100, r.Makespan)
+}
+
+func LoadCluster(path string) (Cluster, error) {
+	var cluster Cluster
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cluster, fmt.Errorf("error reading cluster %s: %v", path, err)
+	}
+	if err = json.Unmarshal(data, &cluster); err != nil {
+		return cluster, fmt.Errorf("error decoding cluster %s: %v", path, err)
+	}
+	return cluster, nil
+}
+
+func LoadTrace(path string) ([]TraceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading trace %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var trace []TraceEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry TraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error decoding trace %s line %d: %v", path, line, err)
+		}
+		trace = append(trace, entry)
+	}
+	return trace, scanner.Err()
+}
+
+func (c Cluster) Build() []*node.Node {
+	var nodes []*node.Node
+	for _, spec := range c.Nodes {
+		count := spec.Count
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			name := spec.Name
+			if count > 1 {
+				name = fmt.Sprintf("%s-%d", spec.Name, i)
+			}
+			n := node.NewNode(name, "", "worker", name)
+			n.Cores = spec.Cores
+			n.Memory = spec.Memory
+			n.Disk = spec.Disk
+			for k, v := range spec.Labels {
+				n.Labels[k] = v
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+type queued struct {
+	task     task.Task
+	arrival  float64
+	duration float64
+}
+
+type running struct {
+	task   task.Task
+	node   *node.Node
+	finish float64
+}
+
+type simulation struct {
+	sched   scheduler.Scheduler
+	nodes   []*node.Node
+	clock   float64
+	pending []queued
+	running []running
+	report  Report
+
+	waitTotal   float64
+	cpuArea     float64
+	memArea     float64
+	fragArea    float64
+	totalCores  float64
+	totalMemory float64
+}
+
+// Run replays the trace against a fresh copy of the cluster on a virtual clock, so a
+// simulated day takes as long as the scheduler needs to make its decisions.
+func Run(sched scheduler.Scheduler, cluster Cluster, trace []TraceEntry) Report {
+	sim := &simulation{
+		sched:  sched,
+		nodes:  cluster.Build(),
+		report: Report{Scheduler: sched.Name(), Tasks: len(trace)},
+	}
+	for _, n := range sim.nodes {
+		sim.totalCores += float64(n.Cores)
+		sim.totalMemory += float64(n.Memory)
+	}
+
+	arrivals := append([]TraceEntry(nil), trace...)
+	sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].Arrival < arrivals[j].Arrival })
+
+	for len(arrivals) > 0 || len(sim.running) > 0 {
+		next := sim.nextFinish()
+		if len(arrivals) > 0 && (len(sim.running) == 0 || arrivals[0].Arrival <= next) {
+			next = arrivals[0].Arrival
+		}
+		sim.advance(next)
+
+		sim.complete()
+		for len(arrivals) > 0 && arrivals[0].Arrival <= sim.clock {
+			sim.pending = append(sim.pending, queued{task: newTask(arrivals[0]), arrival: sim.clock, duration: arrivals[0].Duration})
+			arrivals = arrivals[1:]
+		}
+		sim.schedule()
+	}
+
+	sim.report.Unschedulable = len(sim.pending)
+	sim.report.Makespan = seconds(sim.clock)
+	if sim.report.Placed > 0 {
+		sim.report.MeanQueueWait = seconds(sim.waitTotal / float64(sim.report.Placed))
+	}
+	if sim.clock > 0 {
+		sim.report.CPUUtilization = sim.cpuArea / sim.clock
+		sim.report.MemoryUtilization = sim.memArea / sim.clock
+		sim.report.Fragmentation = sim.fragArea / sim.clock
+	}
+
+	return sim.report
+}
+
+func newTask(entry TraceEntry) task.Task {
+	return task.Task{
+		ID:         uuid.New(),
+		Name:       entry.Name,
+		Labels:     entry.Labels,
+		State:      task.Pending,
+		CPU:        entry.CPU,
+		Memory:     entry.Memory,
+		Disk:       entry.Disk,
+		TaskConfig: task.Config{Name: entry.Name},
+	}
+}
+
+func (sim *simulation) nextFinish() float64 {
+	next := -1.0
+	for _, r := range sim.running {
+		if next < 0 || r.finish < next {
+			next = r.finish
+		}
+	}
+	return next
+}
+
+// advance moves the clock forward, accumulating time-weighted utilisation and fragmentation.
+func (sim *simulation) advance(to float64) {
+	elapsed := to - sim.clock
+	if elapsed > 0 {
+		var cpu, mem, maxFree, totalFree float64
+		for _, n := range sim.nodes {
+			cpu += n.CpuAllocated
+			mem += float64(n.MemoryAllocated)
+			free := float64(n.Memory - n.MemoryAllocated)
+			totalFree += free
+			if free > maxFree {
+				maxFree = free
+			}
+		}
+		if sim.totalCores > 0 {
+			sim.cpuArea += elapsed * cpu / sim.totalCores
+		}
+		if sim.totalMemory > 0 {
+			sim.memArea += elapsed * mem / sim.totalMemory
+		}
+		if totalFree > 0 {
+			sim.fragArea += elapsed * (1 - maxFree/totalFree)
+		}
+	}
+	sim.clock = to
+}
+
+func (sim *simulation) complete() {
+	remaining := sim.running[:0]
+	for _, r := range sim.running {
+		if r.finish <= sim.clock {
+			r.node.Release(r.task)
+			continue
+		}
+		remaining = append(remaining, r)
+	}
+	sim.running = remaining
+}
+
+func (sim *simulation) schedule() {
+	stillPending := sim.pending[:0]
+	for _, q := range sim.pending {
+		sim.observe()
+
+		candidateNodes := sim.sched.SelectCandidateNodes(q.task, sim.nodes)
+		var picked *node.Node
+		if len(candidateNodes) > 0 {
+			scores := sim.sched.ScoreNodes(q.task, candidateNodes, sim.nodes)
+			picked = sim.sched.PickNode(scores, candidateNodes)
+		}
+
+		if picked == nil {
+			sim.report.FailedAttempts++
+			stillPending = append(stillPending, q)
+			continue
+		}
+
+		picked.Allocate(q.task)
+		wait := sim.clock - q.arrival
+		sim.waitTotal += wait
+		if seconds(wait) > sim.report.MaxQueueWait {
+			sim.report.MaxQueueWait = seconds(wait)
+		}
+		sim.report.Placed++
+		sim.running = append(sim.running, running{task: q.task, node: picked, finish: sim.clock + q.duration})
+	}
+	sim.pending = stillPending
+}
+
+// observe feeds each node a synthetic stats sample derived from its allocations,
+// standing in for the samples the manager collects from real workers.
+func (sim *simulation) observe() {
+	for _, n := range sim.nodes {
+		m := metrics.Metrics{}
+		if n.Cores > 0 {
+			m.CPU.RatioUsed = n.CpuAllocated / float64(n.Cores)
+		}
+		m.CPU.Cores = n.Cores
+		m.Memory.Total = uint64(n.Memory)
+		m.Memory.Available = uint64(n.Memory)
+		m.Disk.Total = uint64(n.Disk)
+		n.Stats = m
+		n.StatsHistory = metrics.NewWindow(node.StatsWindow)
+		n.RecordStats(m, time.Now())
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,90 @@
+package task
+
+type Operator string
+
+const (
+	In           Operator = "In"
+	NotIn        Operator = "NotIn"
+	Exists       Operator = "Exists"
+	DoesNotExist Operator = "DoesNotExist"
+)
+
+type LabelExpression struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// NodeSelectorTerm matches when all of its expressions match.
+type NodeSelectorTerm struct {
+	Expressions []LabelExpression
+}
+
+type PreferredNodeTerm struct {
+	Weight int
+	Term   NodeSelectorTerm
+}
+
+// NodeAffinity is satisfied by a node matching any Required term; Preferred terms only influence scoring.
+type NodeAffinity struct {
+	Required  []NodeSelectorTerm
+	Preferred []PreferredNodeTerm
+}
+
+// TaskAffinityTerm selects running tasks by label within the node's TopologyKey domain.
+// An empty TopologyKey means the node itself.
+type TaskAffinityTerm struct {
+	LabelSelector map[string]string
+	TopologyKey   string
+	Required      bool
+	Weight        int
+}
+
+type Affinity struct {
+	Node         NodeAffinity
+	TaskAffinity []TaskAffinityTerm
+	TaskAnti     []TaskAffinityTerm
+}
+
+func (e LabelExpression) Matches(labels map[string]string) bool {
+	value, ok := labels[e.Key]
+
+	switch e.Operator {
+	case In:
+		return ok && contains(e.Values, value)
+	case NotIn:
+		return !ok || !contains(e.Values, value)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+func (nt NodeSelectorTerm) Matches(labels map[string]string) bool {
+	for _, e := range nt.Expressions {
+		if !e.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ta TaskAffinityTerm) Selects(t Task) bool {
+	for k, v := range ta.LabelSelector {
+		if t.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
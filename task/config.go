@@ -9,12 +9,17 @@ type Config struct {
 	AttachStdout  bool
 	AttachStderr  bool
 	ExposedPorts  nat.PortSet
+	PortBindings  nat.PortMap
 	Cmd           []string
 	Image         string
 	Cpu           float64
 	Memory        int64
 	Disk          int64
 	Env           []string
+	Dns           []string
+	DnsSearch     []string
+	Network       NetworkSpec
+	Binds         []string
 	RestartPolicy RestartPolicy
 }
 
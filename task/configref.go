@@ -0,0 +1,30 @@
+package task
+
+import "path"
+
+const ConfigsMountDir = "/etc/orchard/configs"
+
+// ConfigRef injects a manager config into the task's container. With Key set, that value goes
+// into the environment variable Env and/or the file File; without it, every key becomes a file
+// in the directory File. With neither Env nor File the config is mounted at /etc/orchard/configs/<Name>.
+// RestartOnChange redeploys the task whenever the config is updated.
+type ConfigRef struct {
+	Name            string
+	Key             string
+	Env             string
+	File            string
+	RestartOnChange bool
+}
+
+func (r ConfigRef) FilePath() string {
+	if r.File != "" {
+		return r.File
+	}
+	if r.Env != "" {
+		return ""
+	}
+	if r.Key != "" {
+		return path.Join(ConfigsMountDir, r.Name, r.Key)
+	}
+	return path.Join(ConfigsMountDir, r.Name)
+}
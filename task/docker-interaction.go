@@ -57,29 +57,52 @@ func (d *Docker) Run() DockerResult {
 	defer reader.Close()
 	io.Copy(os.Stdout, reader)
 
+	if err := d.ensureNetworks(ctx); err != nil {
+		log.Printf("Error preparing networks for %s: %v\n", d.Config.Name, err)
+		return DockerResult{Error: err}
+	}
+
+	cmd := d.Config.Cmd
+	if len(cmd) == 0 {
+		cmd = []string{"sh"}
+	}
+
 	cc := container.Config{
 		Image:        d.Config.Image,
 		Tty:          false,
 		Env:          d.Config.Env,
 		ExposedPorts: d.Config.ExposedPorts,
-		Cmd:          []string{"sh"},
+		Cmd:          cmd,
 	}
 
 	hc := container.HostConfig{
-		RestartPolicy: container.RestartPolicy{Name: string(d.Config.RestartPolicy)},
+		// Restarts are driven by the manager, Docker must not restart containers behind its back.
+		RestartPolicy: container.RestartPolicy{Name: string(NO)},
 		Resources: container.Resources{
 			Memory:    d.Config.Memory,
 			CPUShares: int64(d.Config.Cpu),
 		},
-		PublishAllPorts: true,
+		PortBindings:    d.Config.PortBindings,
+		DNS:             d.Config.Dns,
+		DNSSearch:       d.Config.DnsSearch,
+		Binds:           d.Config.Binds,
+		PublishAllPorts: len(d.Config.PortBindings) == 0 && !d.Config.Network.PublishListedOnly,
+	}
+	if networks := d.Config.Network.Networks; len(networks) > 0 {
+		hc.NetworkMode = container.NetworkMode(networks[0].Name)
 	}
 
-	res, err := d.Client.ContainerCreate(ctx, &cc, &hc, nil, d.Config.Name)
+	res, err := d.Client.ContainerCreate(ctx, &cc, &hc, d.networkingConfig(), d.Config.Name)
 	if err != nil {
 		log.Printf("Error creating container using image %s: %v\n", d.Config.Image, err)
 		return DockerResult{Error: err}
 	}
 
+	if err := d.connectNetworks(ctx, res.ID); err != nil {
+		log.Printf("Error attaching networks: %v\n", err)
+		return DockerResult{Error: err}
+	}
+
 	err = d.Client.ContainerStart(ctx, res.ID, types.ContainerStartOptions{})
 	if err != nil {
 		log.Printf("Error starting container %s: %v\n", res.ID, err)
@@ -121,6 +144,41 @@ func (d *Docker) Stop(containerId string) DockerResult {
 	return DockerResult{Action: "stop", Result: "success", Error: nil}
 }
 
+// Exec runs cmd inside the container and returns its exit code.
+func (d *Docker) Exec(ctx context.Context, containerId string, cmd []string) (int, error) {
+	exec, err := d.Client.ContainerExecCreate(ctx, containerId, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	attach, err := d.Client.ContainerExecAttach(ctx, exec.ID, types.ExecConfig{})
+	if err != nil {
+		return 0, err
+	}
+	defer attach.Close()
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, attach.Reader)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	inspect, err := d.Client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
 func (d *Docker) Inspect(containerId string) DockerInspectResponse {
 	dc := NewClientFromPool().Client
 	res, err := dc.ContainerInspect(context.Background(), containerId)
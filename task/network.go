@@ -0,0 +1,101 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/docker/engine-api/client"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/network"
+)
+
+// NetworkAttachment joins a task to a named network, where other tasks on it can reach it by
+// container name or any of Aliases. Missing networks are created on the worker with Driver
+// (bridge by default); Internal ones have no route out of the host.
+type NetworkAttachment struct {
+	Name     string
+	Aliases  []string
+	Driver   string
+	Internal bool
+}
+
+// NetworkSpec lists the networks a task joins. Without networks the container runs on Docker's
+// default bridge. With PublishListedOnly only the task's requested ports are published on the
+// host rather than every exposed port.
+type NetworkSpec struct {
+	Networks          []NetworkAttachment
+	PublishListedOnly bool
+}
+
+// ContainerIP returns the container's address on the first of its networks, or on Docker's
+// default bridge when it joined none. Tasks whose ports are not published are reached there.
+func ContainerIP(c *types.ContainerJSON, spec NetworkSpec) string {
+	if c == nil || c.NetworkSettings == nil {
+		return ""
+	}
+	for _, n := range spec.Networks {
+		if ep, ok := c.NetworkSettings.Networks[n.Name]; ok && ep != nil && ep.IPAddress != "" {
+			return ep.IPAddress
+		}
+	}
+	return c.NetworkSettings.IPAddress
+}
+
+// ensureNetworks creates any network the container joins that does not exist yet.
+func (d *Docker) ensureNetworks(ctx context.Context) error {
+	for _, n := range d.Config.Network.Networks {
+		_, err := d.Client.NetworkInspect(ctx, n.Name)
+		if err == nil {
+			continue
+		}
+		if !client.IsErrNetworkNotFound(err) {
+			return fmt.Errorf("inspecting network %s: %v", n.Name, err)
+		}
+
+		driver := n.Driver
+		if driver == "" {
+			driver = "bridge"
+		}
+		_, err = d.Client.NetworkCreate(ctx, n.Name, types.NetworkCreate{
+			CheckDuplicate: true,
+			Driver:         driver,
+			Internal:       n.Internal,
+			Labels:         map[string]string{"orchard": "true"},
+		})
+		if err != nil {
+			return fmt.Errorf("creating network %s: %v", n.Name, err)
+		}
+		log.Printf("Created %s network %s\n", driver, n.Name)
+	}
+	return nil
+}
+
+// networkingConfig attaches the container to its first network at creation; Docker only
+// accepts one there, the rest are connected once the container exists.
+func (d *Docker) networkingConfig() *network.NetworkingConfig {
+	if len(d.Config.Network.Networks) == 0 {
+		return nil
+	}
+
+	n := d.Config.Network.Networks[0]
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			n.Name: {Aliases: n.Aliases},
+		},
+	}
+}
+
+func (d *Docker) connectNetworks(ctx context.Context, containerId string) error {
+	if len(d.Config.Network.Networks) < 2 {
+		return nil
+	}
+
+	for _, n := range d.Config.Network.Networks[1:] {
+		err := d.Client.NetworkConnect(ctx, n.Name, containerId, &network.EndpointSettings{Aliases: n.Aliases})
+		if err != nil {
+			return fmt.Errorf("connecting container %s to network %s: %v", containerId, n.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package task
+
+import (
+	"strconv"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// PortRequest asks for ContainerPort (e.g. "8080/tcp") to be published on the node.
+// A zero HostPort lets the manager pick a free one.
+type PortRequest struct {
+	ContainerPort string
+	HostPort      int
+}
+
+// StaticHostPorts lists the host ports the task asks for explicitly.
+func (t Task) StaticHostPorts() []string {
+	var ports []string
+	for _, p := range t.Ports {
+		if p.HostPort != 0 {
+			ports = append(ports, strconv.Itoa(p.HostPort))
+		}
+	}
+	return ports
+}
+
+// PortMap turns the task's resolved PortBindings into the form the docker client expects.
+func (t Task) PortMap() (nat.PortSet, nat.PortMap) {
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
+	for containerPort, hostPort := range t.PortBindings {
+		port := nat.Port(containerPort)
+		exposed[port] = struct{}{}
+		bindings[port] = []nat.PortBinding{{HostPort: hostPort}}
+	}
+	return exposed, bindings
+}
@@ -0,0 +1,79 @@
+package task
+
+import "time"
+
+type HTTPGetAction struct {
+	Path           string
+	Port           string
+	Method         string
+	Headers        map[string]string
+	ExpectedStatus []int
+}
+
+type TCPSocketAction struct {
+	Port string
+}
+
+type ExecAction struct {
+	Command []string
+}
+
+// Probe checks a running task with exactly one of HTTPGet, TCPSocket or Exec. Ports are container
+// ports, reached through their published host port; an empty port means the first published one.
+// HTTP probes pass on any of ExpectedStatus, or on 2xx and 3xx when it is empty.
+type Probe struct {
+	HTTPGet             *HTTPGetAction
+	TCPSocket           *TCPSocketAction
+	Exec                *ExecAction
+	InitialDelaySeconds int
+	PeriodSeconds       int
+	TimeoutSeconds      int
+	SuccessThreshold    int
+	FailureThreshold    int
+}
+
+func (p *Probe) InitialDelay() time.Duration {
+	return time.Duration(p.InitialDelaySeconds) * time.Second
+}
+
+func (p *Probe) Period() time.Duration {
+	if p.PeriodSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(p.PeriodSeconds) * time.Second
+}
+
+func (p *Probe) Timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+func (p *Probe) Successes() int {
+	if p.SuccessThreshold <= 0 {
+		return 1
+	}
+	return p.SuccessThreshold
+}
+
+func (p *Probe) Failures() int {
+	if p.FailureThreshold <= 0 {
+		return 3
+	}
+	return p.FailureThreshold
+}
+
+// StartupDeadline is how long a startup probe may keep failing before the task is given up on.
+func (p *Probe) StartupDeadline() time.Duration {
+	return p.InitialDelay() + time.Duration(p.Failures())*p.Period()
+}
+
+// Liveness returns the task's liveness probe, turning the older HealthCheck path into
+// an HTTP probe when no probe is set.
+func (t *Task) Liveness() *Probe {
+	if t.LivenessProbe != nil || t.HealthCheck == "" {
+		return t.LivenessProbe
+	}
+	return &Probe{HTTPGet: &HTTPGetAction{Path: t.HealthCheck}}
+}
@@ -0,0 +1,38 @@
+package task
+
+const (
+	RestartNever     = "Never"
+	RestartOnFailure = "OnFailure"
+	RestartAlways    = "Always"
+)
+
+const (
+	DefaultMaxRetries = 3
+	CrashLoopBackOff  = "CrashLoopBackOff"
+)
+
+// Restart returns the restart policy the orchestrator enforces for the task. Tasks without one
+// fall back to the Docker-style policy in their config, and then to OnFailure.
+func (t *Task) Restart() string {
+	switch t.RestartPolicy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+		return t.RestartPolicy
+	}
+
+	switch t.TaskConfig.RestartPolicy {
+	case NO:
+		return RestartNever
+	case ALWAYS, UNLESS_STOPPED:
+		return RestartAlways
+	default:
+		return RestartOnFailure
+	}
+}
+
+// Retries returns how many times the task may be restarted; a negative MaxRetries means no limit.
+func (t *Task) Retries() int {
+	if t.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+	return t.MaxRetries
+}
@@ -0,0 +1,23 @@
+package task
+
+import "path"
+
+const SecretsMountDir = "/run/secrets"
+
+// SecretRef injects a manager secret into the task's container as the environment variable Env,
+// the file File, or both. With neither set it is mounted at /run/secrets/<Name>.
+type SecretRef struct {
+	Name string
+	Env  string
+	File string
+}
+
+func (r SecretRef) FilePath() string {
+	if r.File != "" {
+		return r.File
+	}
+	if r.Env != "" {
+		return ""
+	}
+	return path.Join(SecretsMountDir, r.Name)
+}
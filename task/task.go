@@ -38,15 +38,16 @@ func (s State) String() string {
 
 var TaskFSM = FSM[State, Event]{
 	transitionListing: map[State][]State{
-		Pending:   {Scheduled},
-		Scheduled: {Scheduled, Running, Failed},
+		Pending:   {Scheduled, Completed},
+		Scheduled: {Scheduled, Running, Completed, Failed},
 		Running:   {Running, Completed, Failed},
 		Completed: {},
 		Dropped:   {},
 	},
 	nextMapping: map[State]map[Event]State{
 		Pending: {
-			SpinUp: Scheduled,
+			SpinUp:   Scheduled,
+			SpinDown: Completed,
 		},
 		Scheduled: {
 			SpinUp:   Running,
@@ -61,24 +62,65 @@ var TaskFSM = FSM[State, Event]{
 }
 
 type Task struct {
-	ID            uuid.UUID
-	Name          string
-	State         State
-	Event         Event
-	Image         string
-	CPU           float64
-	Memory        int
-	Disk          int
-	ExposedPorts  nat.PortSet
-	HostPorts     nat.PortMap
-	PortBindings  map[string]string
-	StartTime     time.Time
-	FinishTime    time.Time
-	ContainerId   string
-	TaskConfig    Config
-	RestartPolicy string
-	HealthCheck   string
-	RestartCount  int
+	ID             uuid.UUID
+	Name           string
+	Priority       int
+	Labels         map[string]string
+	NodeSelector   map[string]string
+	Affinity       Affinity
+	Tolerations    []Toleration
+	Gang           GangSpec
+	Owner          Owner
+	State          State
+	Event          Event
+	Image          string
+	CPU            float64
+	Memory         int
+	Disk           int
+	ExposedPorts   nat.PortSet
+	Ports          []PortRequest
+	HostPorts      nat.PortMap
+	PortBindings   map[string]string
+	ContainerIP    string
+	Network        NetworkSpec
+	StartTime      time.Time
+	FinishTime     time.Time
+	ContainerId    string
+	TaskConfig     Config
+	RestartPolicy  string
+	MaxRetries     int
+	HealthCheck    string
+	StartupProbe   *Probe
+	LivenessProbe  *Probe
+	ReadinessProbe *Probe
+	WaitFor        []string
+	Secrets        []SecretRef
+	Configs        []ConfigRef
+	RestartCount   int
+	NextRestart    time.Time
+	ExitCode       int
+	Ready          bool
+	Reason         string
+}
+
+const (
+	OwnerService  = "Service"
+	OwnerJob      = "Job"
+	OwnerWorkflow = "Workflow"
+)
+
+// Owner names the resource a task was created for, if any.
+type Owner struct {
+	Kind     string
+	Name     string
+	Revision int
+}
+
+// GangSpec places Size tasks sharing Name all together or not at all.
+type GangSpec struct {
+	Name           string
+	Size           int
+	TimeoutSeconds int
 }
 
 func NewConfig(t *Task) Config {
@@ -86,7 +128,24 @@ func NewConfig(t *Task) Config {
 		return Config{}
 	}
 
-	return t.TaskConfig
+	config := t.TaskConfig
+	if len(t.PortBindings) > 0 {
+		exposed, bindings := t.PortMap()
+		// Copied, as the set is shared with the stored task.
+		exposedPorts := make(nat.PortSet, len(config.ExposedPorts)+len(exposed))
+		for port := range config.ExposedPorts {
+			exposedPorts[port] = struct{}{}
+		}
+		config.ExposedPorts = exposedPorts
+		for port := range exposed {
+			config.ExposedPorts[port] = struct{}{}
+		}
+		config.PortBindings = bindings
+	}
+	if len(t.Network.Networks) > 0 || t.Network.PublishListedOnly {
+		config.Network = t.Network
+	}
+	return config
 }
 
 type TaskEvent struct {
@@ -94,4 +153,9 @@ type TaskEvent struct {
 	State     State
 	Timestamp time.Time
 	Task      Task
+	Reason    string
+	// Secrets carries secret values to the worker placing the task, and is empty everywhere else.
+	Secrets map[string][]byte `json:",omitempty"`
+	// Configs carries the data of the task's configs to its worker.
+	Configs map[string]map[string]string `json:",omitempty"`
 }
@@ -0,0 +1,17 @@
+package task
+
+type TolerationOperator string
+
+const (
+	TolerateEqual  TolerationOperator = "Equal"
+	TolerateExists TolerationOperator = "Exists"
+)
+
+// Toleration matches node taints by key, and by value unless Operator is Exists.
+// An empty Effect tolerates every effect; an empty Key with Exists tolerates every taint.
+type Toleration struct {
+	Key      string
+	Operator TolerationOperator
+	Value    string
+	Effect   string
+}
@@ -40,7 +40,7 @@ func (httpApiWorker *HttpApiWorker) StartTaskHandler(w http.ResponseWriter, r *h
 	ts.Task.State = task.Pending
 	ts.Task.Event = task.SpinUp
 
-	httpApiWorker.Ref.AddTask(ts.Task)
+	httpApiWorker.Ref.SubmitTask(ts.Task, ts.Secrets, ts.Configs)
 	log.Printf("Added task %v\n", ts.Task.ID)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(api.StandardResponse[task.Task]{
@@ -66,7 +66,7 @@ func (httpApiWorker *HttpApiWorker) StopTaskHandler(w http.ResponseWriter, r *ht
 	}
 
 	tID, _ := uuid.Parse(taskId)
-	taskToStop, ok := httpApiWorker.Ref.Db[tID]
+	taskCopy, ok := httpApiWorker.Ref.StopTaskByID(tID)
 
 	if !ok {
 		log.Printf("No task with ID %v found", tID)
@@ -79,10 +79,6 @@ func (httpApiWorker *HttpApiWorker) StopTaskHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	taskCopy := *taskToStop
-	taskCopy.Event = task.SpinDown
-	httpApiWorker.Ref.AddTask(taskCopy)
-
 	w.WriteHeader(http.StatusOK)
 
 	json.NewEncoder(w).Encode(api.StandardResponse[task.Task]{
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// ConfigsDir holds the files of configs mounted into tasks.
+var ConfigsDir = filepath.Join(os.TempDir(), "orchard-configs")
+
+// injectConfigs adds the task's configs to its container config. A reference with a key
+// becomes an environment variable and/or a single file; one without a key is mounted as a
+// directory holding a file per key.
+func (w *Worker) injectConfigs(t *task.Task, config *task.Config) error {
+	if len(t.Configs) == 0 {
+		return nil
+	}
+
+	data := w.configs[t.ID]
+	dir := filepath.Join(ConfigsDir, t.ID.String())
+	for i, ref := range t.Configs {
+		values, ok := data[ref.Name]
+		if !ok {
+			return fmt.Errorf("config %s was not delivered with task %v", ref.Name, t.ID)
+		}
+
+		if ref.Key == "" {
+			if ref.Env != "" {
+				return fmt.Errorf("config %s needs a key to be set as %s", ref.Name, ref.Env)
+			}
+			src := filepath.Join(dir, fmt.Sprint(i))
+			if err := os.MkdirAll(src, 0755); err != nil {
+				return err
+			}
+			keys := make([]string, 0, len(values))
+			for k := range values {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if err := os.WriteFile(filepath.Join(src, filepath.Base(k)), []byte(values[k]), 0444); err != nil {
+					return err
+				}
+			}
+			config.Binds = append(config.Binds, fmt.Sprintf("%s:%s:ro", src, ref.FilePath()))
+			continue
+		}
+
+		value, ok := values[ref.Key]
+		if !ok {
+			return fmt.Errorf("config %s has no key %s", ref.Name, ref.Key)
+		}
+		if ref.Env != "" {
+			config.Env = append(config.Env, fmt.Sprintf("%s=%s", ref.Env, value))
+		}
+		if target := ref.FilePath(); target != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			file := filepath.Join(dir, fmt.Sprint(i))
+			if err := os.WriteFile(file, []byte(value), 0444); err != nil {
+				return err
+			}
+			config.Binds = append(config.Binds, fmt.Sprintf("%s:%s:ro", file, target))
+		}
+	}
+	return nil
+}
+
+// removeConfigs forgets a task's config data and deletes its config files.
+func (w *Worker) removeConfigs(id uuid.UUID) {
+	delete(w.configs, id)
+	if err := os.RemoveAll(filepath.Join(ConfigsDir, id.String())); err != nil {
+		log.Printf("Error removing config files of task %v: %v\n", id, err)
+	}
+}
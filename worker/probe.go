@@ -0,0 +1,270 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"orchard/task"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	Startup   = "startup"
+	Liveness  = "liveness"
+	Readiness = "readiness"
+)
+
+type probeKey struct {
+	ID   uuid.UUID
+	Kind string
+}
+
+// probeState tracks consecutive results so a probe only flips once its threshold is reached.
+type probeState struct {
+	lastRun   time.Time
+	successes int
+	failures  int
+	healthy   bool
+	lastErr   error
+	passedAt  time.Time
+}
+
+func (w *Worker) ProbeTasksPeriodically() {
+	ticker := time.NewTicker(time.Second)
+	for range ticker.C {
+		w.ProbeTasks()
+	}
+}
+
+// ProbeTasks runs every due probe of the worker's running tasks. Until a task's startup probe
+// passes its other probes are held off. A task without a readiness probe is ready as soon as it
+// has started; a task failing its liveness probe, or its startup probe past the startup deadline,
+// is stopped and marked Failed so the manager's restart policy takes over. Probes run on copies
+// of the tasks, without the lock; probes is only ever touched by this loop.
+func (w *Worker) ProbeTasks() {
+	if w.probes == nil {
+		w.probes = make(map[probeKey]*probeState)
+	}
+
+	now := time.Now()
+	running := make(map[uuid.UUID]bool)
+	for _, t := range w.running() {
+		running[t.ID] = true
+
+		ready, failed, err := w.probeTask(&t, now)
+		if failed != "" {
+			w.failProbe(t, failed, err)
+			continue
+		}
+
+		w.mu.Lock()
+		if current, ok := w.Db[t.ID]; ok && current.State == task.Running && current.ContainerId == t.ContainerId {
+			current.Ready = ready
+		}
+		w.mu.Unlock()
+	}
+
+	for key := range w.probes {
+		if !running[key.ID] {
+			delete(w.probes, key)
+		}
+	}
+}
+
+// probeTask runs the task's due probes and reports whether it is ready, or which probe failed
+// and why when the task has to be stopped.
+func (w *Worker) probeTask(t *task.Task, now time.Time) (bool, string, error) {
+	if p := t.StartupProbe; p != nil {
+		started, err := w.probe(t, Startup, p, now)
+		if !started {
+			if err != nil && now.Sub(t.StartTime) > p.StartupDeadline() {
+				return false, Startup, err
+			}
+			return false, "", nil
+		}
+	}
+
+	ready := true
+	if t.ReadinessProbe != nil {
+		ready, _ = w.probe(t, Readiness, t.ReadinessProbe, now)
+	}
+
+	if p := t.Liveness(); p != nil {
+		if alive, err := w.probe(t, Liveness, p, now); !alive {
+			return false, Liveness, err
+		}
+	}
+	return ready, "", nil
+}
+
+func (w *Worker) probe(t *task.Task, kind string, p *task.Probe, now time.Time) (bool, error) {
+	key := probeKey{t.ID, kind}
+	s, ok := w.probes[key]
+	if !ok {
+		// Liveness is assumed until proven otherwise, startup and readiness have to be earned.
+		s = &probeState{healthy: kind == Liveness}
+		w.probes[key] = s
+	}
+	if kind == Startup && s.healthy {
+		return true, nil
+	}
+
+	// Once a startup probe has passed, the other probes' initial delay counts from then.
+	since := t.StartTime
+	if started, ok := w.probes[probeKey{t.ID, Startup}]; ok && kind != Startup && !started.passedAt.IsZero() {
+		since = started.passedAt
+	}
+	if now.Before(since.Add(p.InitialDelay())) || now.Sub(s.lastRun) < p.Period() {
+		return s.healthy, s.lastErr
+	}
+	s.lastRun = now
+
+	if err := check(t, p); err != nil {
+		s.successes = 0
+		s.failures++
+		s.lastErr = err
+		if s.failures >= p.Failures() {
+			if s.healthy {
+				log.Printf("Task %v failed %s probe %d times: %v\n", t.ID, kind, s.failures, err)
+			}
+			s.healthy = false
+		}
+	} else {
+		s.failures = 0
+		s.successes++
+		s.lastErr = nil
+		if s.successes >= p.Successes() {
+			if !s.healthy {
+				log.Printf("Task %v passed %s probe\n", t.ID, kind)
+				s.passedAt = now
+			}
+			s.healthy = true
+		}
+	}
+	return s.healthy, s.lastErr
+}
+
+func (w *Worker) failProbe(t task.Task, kind string, err error) {
+	d := task.NewClientFromPool()
+	d.Stop(t.ContainerId)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current, ok := w.Db[t.ID]
+	if !ok || current.ContainerId != t.ContainerId {
+		return
+	}
+	w.removeSecrets(t.ID)
+	w.removeConfigs(t.ID)
+
+	current.State = task.Failed
+	current.Ready = false
+	current.FinishTime = time.Now().UTC()
+	current.Reason = fmt.Sprintf("%s probe failed: %v", kind, err)
+	log.Printf("Stopped task %v: %s\n", t.ID, current.Reason)
+}
+
+func check(t *task.Task, p *task.Probe) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout())
+	defer cancel()
+
+	switch {
+	case p.HTTPGet != nil:
+		return checkHTTP(ctx, t, p.HTTPGet)
+	case p.TCPSocket != nil:
+		addr, err := probeAddress(t, p.TCPSocket.Port)
+		if err != nil {
+			return err
+		}
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case p.Exec != nil:
+		code, err := task.NewClientFromPool().Exec(ctx, t.ContainerId, p.Exec.Command)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("%s exited with code %d", strings.Join(p.Exec.Command, " "), code)
+		}
+	}
+	return nil
+}
+
+func checkHTTP(ctx context.Context, t *task.Task, action *task.HTTPGetAction) error {
+	addr, err := probeAddress(t, action.Port)
+	if err != nil {
+		return err
+	}
+
+	method := action.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := action.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://%s%s", addr, path), nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range action.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if len(action.ExpectedStatus) == 0 {
+		if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+			return nil
+		}
+	}
+	for _, status := range action.ExpectedStatus {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s returned %d", method, path, resp.StatusCode)
+}
+
+// probeAddress finds the host address a container port is published on. Ports that are not
+// published, as on tasks only reachable over their networks, are probed on the container's IP.
+func probeAddress(t *task.Task, port string) (string, error) {
+	for p, bindings := range t.HostPorts {
+		if len(bindings) == 0 || bindings[0].HostPort == "" {
+			continue
+		}
+		if port == "" || string(p) == port || p.Port() == port {
+			return net.JoinHostPort("127.0.0.1", bindings[0].HostPort), nil
+		}
+	}
+
+	if t.ContainerIP == "" {
+		if port == "" {
+			return "", fmt.Errorf("task %v has no published port to probe", t.ID)
+		}
+		return "", fmt.Errorf("port %s of task %v is not published", port, t.ID)
+	}
+	if port == "" {
+		for p := range t.HostPorts {
+			return net.JoinHostPort(t.ContainerIP, p.Port()), nil
+		}
+		return "", fmt.Errorf("task %v exposes no port to probe", t.ID)
+	}
+	containerPort, _, _ := strings.Cut(port, "/")
+	return net.JoinHostPort(t.ContainerIP, containerPort), nil
+}
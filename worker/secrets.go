@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"orchard/task"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// SecretsDir holds secret files on a tmpfs so they never touch the worker's disk.
+var SecretsDir = "/dev/shm/orchard-secrets"
+
+// injectSecrets adds the task's secrets to its container config: environment variables
+// directly, files as read-only bind mounts of per-task files under SecretsDir.
+func (w *Worker) injectSecrets(t *task.Task, config *task.Config) error {
+	if len(t.Secrets) == 0 {
+		return nil
+	}
+
+	values := w.secrets[t.ID]
+	dir := filepath.Join(SecretsDir, t.ID.String())
+	for i, ref := range t.Secrets {
+		value, ok := values[ref.Name]
+		if !ok {
+			return fmt.Errorf("secret %s was not delivered with task %v", ref.Name, t.ID)
+		}
+
+		if ref.Env != "" {
+			config.Env = append(config.Env, fmt.Sprintf("%s=%s", ref.Env, value))
+		}
+
+		if target := ref.FilePath(); target != "" {
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return err
+			}
+			file := filepath.Join(dir, fmt.Sprint(i))
+			if err := os.WriteFile(file, value, 0400); err != nil {
+				return err
+			}
+			config.Binds = append(config.Binds, fmt.Sprintf("%s:%s:ro", file, target))
+		}
+	}
+	return nil
+}
+
+// removeSecrets forgets a task's secrets and deletes its secret files.
+func (w *Worker) removeSecrets(id uuid.UUID) {
+	delete(w.secrets, id)
+	if err := os.RemoveAll(filepath.Join(SecretsDir, id.String())); err != nil {
+		log.Printf("Error removing secret files of task %v: %v\n", id, err)
+	}
+}
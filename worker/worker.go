@@ -6,6 +6,7 @@ import (
 	"log"
 	"orchard/metrics"
 	"orchard/task"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +19,14 @@ type Worker struct {
 	Queue     queue.Queue
 	Db        map[uuid.UUID]*task.Task
 	TaskCount atomic.Int32
+
+	probes  map[probeKey]*probeState
+	secrets map[uuid.UUID]map[string][]byte
+	configs map[uuid.UUID]map[string]map[string]string
+
+	// mu guards the queue, Db, secrets and configs, which the task loop, the update and
+	// probe loops and the API handlers share. It is never held while talking to Docker.
+	mu sync.Mutex
 }
 
 func (w *Worker) CollectStats() {
@@ -29,17 +38,34 @@ func (w *Worker) CollectStats() {
 }
 
 func (w *Worker) RunTask() task.DockerResult {
+	taskQueued, result, ok := w.nextTask()
+	if !ok {
+		return result
+	}
+
+	if taskQueued.State == task.Scheduled {
+		return w.StartTask(taskQueued)
+	}
+	return w.StopTask(taskQueued)
+}
+
+// nextTask takes the next task off the queue and checks its transition, moving Pending tasks
+// along itself. It reports whether the task is left for RunTask to start or stop.
+func (w *Worker) nextTask() (task.Task, task.DockerResult, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	t := w.Queue.Dequeue()
 
 	if t == nil {
 		log.Printf("No task found")
-		return task.DockerResult{Error: nil}
+		return task.Task{}, task.DockerResult{Error: nil}, false
 	}
 
 	taskQueued, ok := t.(task.Task)
 
 	if !ok {
-		return task.DockerResult{Error: fmt.Errorf("%+v type casting error", t)}
+		return task.Task{}, task.DockerResult{Error: fmt.Errorf("%+v type casting error", t)}, false
 	}
 
 	taskPersisted := w.Db[taskQueued.ID]
@@ -49,28 +75,22 @@ func (w *Worker) RunTask() task.DockerResult {
 		w.Db[taskQueued.ID] = &taskQueued
 	}
 
-	var result task.DockerResult
-
 	_, _, nextState := task.TaskFSM.Next(taskPersisted.State, taskQueued.Event)
 
-	if task.TaskFSM.ValidStateTransition(taskPersisted.State, nextState) {
-		switch taskQueued.State {
-		case task.Pending:
-			result = task.DockerResult{Result: fmt.Sprintf("%s task moved to %s", taskPersisted.ID, nextState)}
-			taskPersisted.State = nextState
-			w.AddTask(*taskPersisted)
-		case task.Scheduled:
-			result = w.StartTask(taskQueued)
-		case task.Completed:
-			result = w.StopTask(taskQueued)
-		default:
-			result.Error = errors.New("we should not get here")
-		}
-	} else {
-		result.Error = fmt.Errorf("invalid transition from %v to %v", taskPersisted.State, taskQueued.State)
+	if !task.TaskFSM.ValidStateTransition(taskPersisted.State, nextState) {
+		return taskQueued, task.DockerResult{Error: fmt.Errorf("invalid transition from %v to %v", taskPersisted.State, taskQueued.State)}, false
 	}
 
-	return result
+	switch taskQueued.State {
+	case task.Pending:
+		taskPersisted.State = nextState
+		w.Queue.Enqueue(*taskPersisted)
+		return taskQueued, task.DockerResult{Result: fmt.Sprintf("%s task moved to %s", taskPersisted.ID, nextState)}, false
+	case task.Scheduled, task.Completed:
+		return taskQueued, task.DockerResult{}, true
+	default:
+		return taskQueued, task.DockerResult{Error: errors.New("we should not get here")}, false
+	}
 }
 
 func (w *Worker) RunTaskPeriodically() {
@@ -78,25 +98,107 @@ func (w *Worker) RunTaskPeriodically() {
 
 	for range ticker.C {
 		log.Println("Tick Worker")
-		if w.Queue.Len() != 0 {
-			result := w.RunTask()
-			if result.Error != nil {
-				log.Printf("Error running task: %v\n", result.Error)
-			}
-		} else {
-			log.Printf("No tasks to process currently.\n")
+		result := w.RunTask()
+		if result.Error != nil {
+			log.Printf("Error running task: %v\n", result.Error)
 		}
 	}
 }
 
 func (w *Worker) AddTask(t task.Task) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Queue.Enqueue(t)
+}
+
+// StopTaskByID queues a stop of the task with the given ID, returning the queued copy.
+func (w *Worker) StopTaskByID(id uuid.UUID) (task.Task, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	t, ok := w.Db[id]
+	if !ok {
+		return task.Task{}, false
+	}
+	taskCopy := *t
+	taskCopy.Event = task.SpinDown
+	taskCopy.State = task.Completed
+	w.Queue.Enqueue(taskCopy)
+	return taskCopy, true
+}
+
+// SubmitTask queues a task sent by the manager, keeping its secrets and configs in memory until it starts.
+// A task the worker already ran is being restarted: its old container is removed and it
+// starts over from Pending.
+func (w *Worker) SubmitTask(t task.Task, secrets map[string][]byte, configs map[string]map[string]string) {
+	w.mu.Lock()
+	existing, restart := w.Db[t.ID]
+	restart = restart && existing.State != task.Pending && existing.State != task.Scheduled
+	var old task.Task
+	if restart {
+		old = *existing
+	}
+	w.mu.Unlock()
+
+	if restart && old.ContainerId != "" {
+		d, err := task.NewDocker(task.NewConfig(&old))
+		if err != nil {
+			log.Printf("Error creating client to remove container %v: %v\n", old.ContainerId, err)
+		} else {
+			d.Stop(old.ContainerId)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(secrets) > 0 {
+		if w.secrets == nil {
+			w.secrets = make(map[uuid.UUID]map[string][]byte)
+		}
+		w.secrets[t.ID] = secrets
+	}
+	if len(configs) > 0 {
+		if w.configs == nil {
+			w.configs = make(map[uuid.UUID]map[string]map[string]string)
+		}
+		w.configs[t.ID] = configs
+	}
+
+	if restart {
+		log.Printf("Restarting task %v (attempt %d)\n", t.ID, t.RestartCount)
+		t.ContainerId = ""
+		w.Db[t.ID] = &t
+	}
 	w.Queue.Enqueue(t)
 }
 
 func (w *Worker) StartTask(t task.Task) task.DockerResult {
 	t.StartTime = time.Now().UTC()
 
-	d, err := task.NewDocker(task.NewConfig(&t))
+	config := task.NewConfig(&t)
+	w.mu.Lock()
+	err := w.injectSecrets(&t, &config)
+	w.mu.Unlock()
+	if err != nil {
+		log.Printf("Err injecting secrets into task %v: %v\n", t.ID, err)
+		t.State = task.Failed
+		t.Reason = err.Error()
+		w.record(t)
+		return task.DockerResult{Error: err}
+	}
+	w.mu.Lock()
+	err = w.injectConfigs(&t, &config)
+	w.mu.Unlock()
+	if err != nil {
+		log.Printf("Err injecting configs into task %v: %v\n", t.ID, err)
+		t.State = task.Failed
+		t.Reason = err.Error()
+		w.record(t)
+		return task.DockerResult{Error: err}
+	}
+
+	d, err := task.NewDocker(config)
 
 	if err != nil {
 		log.Printf("Error creating client to stop task")
@@ -110,9 +212,15 @@ func (w *Worker) StartTask(t task.Task) task.DockerResult {
 	} else {
 		t.ContainerId = res.ContainerId
 		t.State = task.Running
+		t.Ready = false
+		t.Reason = ""
+		if inspect := w.InspectTask(t); inspect.Error == nil && inspect.Container != nil {
+			t.HostPorts = inspect.Container.NetworkSettings.NetworkSettingsBase.Ports
+			t.ContainerIP = task.ContainerIP(inspect.Container, t.Network)
+		}
 	}
 
-	w.Db[t.ID] = &t
+	w.record(t)
 	return res
 
 }
@@ -132,13 +240,29 @@ func (w *Worker) StopTask(t task.Task) task.DockerResult {
 
 	t.FinishTime = time.Now().UTC()
 	t.State = task.Completed
-	w.Db[t.ID] = &t
+	w.record(t)
 
 	log.Printf("Stopped and removed container %v for task %v\n", t.ContainerId, t.ID)
 	return res
 }
 
+// record stores the outcome of starting or stopping a task. A task left not running has no
+// more use for its secrets and configs, so they are removed.
+func (w *Worker) record(t task.Task) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t.State != task.Running {
+		w.removeSecrets(t.ID)
+		w.removeConfigs(t.ID)
+	}
+	w.Db[t.ID] = &t
+}
+
 func (w *Worker) ListTasks() []task.Task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	values := make([]task.Task, 0, len(w.Db))
 	for _, v := range w.Db {
 		values = append(values, *v)
@@ -148,6 +272,9 @@ func (w *Worker) ListTasks() []task.Task {
 }
 
 func (w *Worker) ListTaskIds() []uuid.UUID {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	keys := make([]uuid.UUID, 0, len(w.Db))
 	for u := range w.Db {
 		keys = append(keys, u)
@@ -157,7 +284,9 @@ func (w *Worker) ListTaskIds() []uuid.UUID {
 }
 
 func (w *Worker) GetTask(taskId uuid.UUID) task.DockerInspectResponse {
+	w.mu.Lock()
 	taskInfo := w.Db[taskId]
+	w.mu.Unlock()
 
 	return task.NewClientFromPool().Inspect(taskInfo.ContainerId)
 }
@@ -176,30 +305,57 @@ func (w *Worker) UpdateTasksPeriodically() {
 
 }
 
+// UpdateTasks inspects the containers of running tasks, recording any that exited. Containers
+// are inspected without the lock, and a result is dropped if the task moved on meanwhile.
 func (w *Worker) UpdateTasks() {
-	for k, v := range w.Db {
-		if v.State != task.Running {
-			return
-		}
-
-		resp := w.InspectTask(*v)
+	for _, v := range w.running() {
+		k := v.ID
+		resp := w.InspectTask(v)
 		if resp.Error != nil {
 			fmt.Printf("ERROR: %v\n", resp.Error)
 			continue
 		}
 
-		if resp.Container == nil {
-			log.Printf("No container for running task %s\n", k)
-			w.Db[k].State = task.Failed
+		w.mu.Lock()
+		current, ok := w.Db[k]
+		if !ok || current.State != task.Running || current.ContainerId != v.ContainerId {
+			w.mu.Unlock()
 			continue
 		}
 
-		if resp.Container.State.Status == "exited" {
-			log.Printf("Container for task %s in non-running state %s", k, resp.Container.State.Status)
-			w.Db[k].State = task.Failed
-			continue
+		switch {
+		case resp.Container == nil:
+			log.Printf("No container for running task %s\n", k)
+			current.State = task.Failed
+		case resp.Container.State.Status == "exited":
+			log.Printf("Container for task %s exited with code %d", k, resp.Container.State.ExitCode)
+			current.ExitCode = resp.Container.State.ExitCode
+			current.FinishTime = time.Now().UTC()
+			w.removeSecrets(k)
+			w.removeConfigs(k)
+			if resp.Container.State.ExitCode == 0 {
+				current.State = task.Completed
+			} else {
+				current.State = task.Failed
+			}
+		default:
+			current.HostPorts = resp.Container.NetworkSettings.NetworkSettingsBase.Ports
+			current.ContainerIP = task.ContainerIP(resp.Container, v.Network)
 		}
+		w.mu.Unlock()
+	}
+}
 
-		w.Db[k].HostPorts = resp.Container.NetworkSettings.NetworkSettingsBase.Ports
+// running returns copies of the tasks that are running.
+func (w *Worker) running() []task.Task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var tasks []task.Task
+	for _, t := range w.Db {
+		if t.State == task.Running {
+			tasks = append(tasks, *t)
+		}
 	}
+	return tasks
 }